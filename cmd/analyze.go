@@ -0,0 +1,167 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/docker/go-units"
+	"github.com/spf13/cobra"
+	"github.com/zawachte-msft/bupkis/pkg/analysis"
+	"github.com/zawachte-msft/bupkis/pkg/formatter"
+	"github.com/zawachte-msft/bupkis/pkg/registry"
+)
+
+type analyzeOptions struct {
+	hostname string
+	top      int
+}
+
+var analyzeOpts = &analyzeOptions{}
+
+var analyzeCmd = &cobra.Command{
+	Use:   "analyze",
+	Short: "analyze how images in container registries relate to each other",
+	Long:  "analyze how images in container registries relate to each other",
+}
+
+var analyzeSharedCmd = &cobra.Command{
+	Use:   "shared [host]",
+	Short: "show layers and manifests shared between repositories",
+	Long:  "show which layers are shared by which repositories, which repositories contain identical manifests, and how much storage the sharing saves",
+	Example: "	bupkis analyze shared bupkisimages.azurecr.io",
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 0 {
+			analyzeOpts.hostname = args[0]
+		}
+
+		return runAnalyzeShared()
+	},
+}
+
+func init() {
+	analyzeSharedCmd.Flags().IntVarP(&analyzeOpts.top, "top", "", 20, "number of layers and manifests to show, 0 for all")
+	analyzeCmd.AddCommand(analyzeSharedCmd)
+	RootCmd.AddCommand(analyzeCmd)
+}
+
+func runAnalyzeShared() error {
+
+	images, err := getAllImages(analyzeOpts.hostname, true)
+	if err != nil {
+		return err
+	}
+
+	sharedLayers := analysis.SharedLayers(images)
+	duplicates := analysis.DuplicateManifests(images)
+
+	// registries do not share storage, so savings are only added up per registry
+	savings := map[string]int64{}
+	for _, layer := range sharedLayers {
+		for hostname, registrySavings := range layer.SavingsByRegistry() {
+			savings[hostname] += registrySavings
+		}
+	}
+
+	layerData := [][]string{}
+	for i, layer := range sharedLayers {
+		if analyzeOpts.top > 0 && i == analyzeOpts.top {
+			break
+		}
+		layerData = append(layerData, []string{
+			layer.Digest.String(),
+			units.HumanSize(float64(layer.Size)),
+			strconv.Itoa(len(layer.Repositories)),
+			units.HumanSize(float64(layer.Savings())),
+			summarizeList(layer.Repositories, 5),
+		})
+	}
+	formatter.PrintTable([]string{"Layer", "Size", "Repositories", "Saved", "Used By"}, layerData)
+	fmt.Println()
+
+	duplicateData := [][]string{}
+	for i, duplicate := range duplicates {
+		if analyzeOpts.top > 0 && i == analyzeOpts.top {
+			break
+		}
+		images := []string{}
+		for _, image := range duplicate.Images {
+			images = append(images, fmt.Sprintf("%s/%s:%s", image.Hostname, image.Name, image.Tag))
+		}
+		duplicateData = append(duplicateData, []string{
+			duplicate.Digest.String(),
+			units.HumanSize(float64(duplicate.Size)),
+			strconv.Itoa(len(duplicate.Repositories())),
+			summarizeList(images, 5),
+		})
+	}
+	formatter.PrintTable([]string{"Manifest", "Size", "Repositories", "Tagged As"}, duplicateData)
+	fmt.Println()
+
+	if len(savings) > 1 {
+		fmt.Printf("%d layers are shared between repositories. %d manifests are duplicated across repositories.\n", len(sharedLayers), len(duplicates))
+
+		hostnames := []string{}
+		for hostname := range savings {
+			hostnames = append(hostnames, hostname)
+		}
+		sort.Strings(hostnames)
+		for _, hostname := range hostnames {
+			fmt.Printf("Sharing saves %s in %s.\n", units.HumanSize(float64(savings[hostname])), hostname)
+		}
+
+		return nil
+	}
+
+	totalSavings := int64(0)
+	for _, registrySavings := range savings {
+		totalSavings += registrySavings
+	}
+	fmt.Printf("%d layers are shared between repositories, saving %s. %d manifests are duplicated across repositories.\n",
+		len(sharedLayers), units.HumanSize(float64(totalSavings)), len(duplicates))
+
+	return nil
+}
+
+// getAllImages lists every image of the registry, or of every registry with
+// credentials when hostname is empty. allPlatforms reads every platform of an
+// index instead of only the default one.
+func getAllImages(hostname string, allPlatforms bool) ([]registry.ImageData, error) {
+
+	client, err := newRegistryClient(registry.RegistryClientOptions{Hostname: hostname, AllPlatforms: allPlatforms})
+	if err != nil {
+		return nil, err
+	}
+
+	if hostname != "" {
+		return client.GetReposByHostName(hostname)
+	}
+
+	return client.GetRepos()
+}
+
+// summarizeList joins items, cutting the list short after limit items
+func summarizeList(items []string, limit int) string {
+	if len(items) <= limit {
+		return strings.Join(items, ", ")
+	}
+
+	return fmt.Sprintf("%s and %d more", strings.Join(items[:limit], ", "), len(items)-limit)
+}
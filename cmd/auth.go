@@ -0,0 +1,159 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	authapi "github.com/zawachte-msft/bupkis/pkg/auth"
+	auth "github.com/zawachte-msft/bupkis/pkg/auth/docker"
+	"github.com/zawachte-msft/bupkis/pkg/formatter"
+	"github.com/zawachte-msft/bupkis/pkg/registry"
+)
+
+// Results of bupkis auth check
+const (
+	credentialValid   = "valid"
+	credentialExpired = "expired"
+	credentialDenied  = "denied"
+)
+
+var authCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "manage the stored registry credentials",
+	Long:  "manage the stored registry credentials",
+}
+
+var authListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "list the registries with stored credentials",
+	Long:  "list the registries with stored credentials, their username and where the credential is kept. Secrets are never printed.",
+	Example: "	bupkis auth list",
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runAuthList()
+	},
+}
+
+var authCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "check the stored credentials against their registries",
+	Long:  "check every stored credential against the /v2/ endpoint of its registry and report whether it is valid, expired or denied",
+	Example: "	bupkis auth check",
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runAuthCheck()
+	},
+}
+
+func init() {
+	authCmd.AddCommand(authListCmd)
+	authCmd.AddCommand(authCheckCmd)
+	RootCmd.AddCommand(authCmd)
+}
+
+func runAuthList() error {
+
+	credentials, err := listCredentials()
+	if err != nil {
+		return err
+	}
+
+	data := [][]string{}
+	for _, credential := range credentials {
+		data = append(data, []string{credential.Hostname, describeUsername(credential), credential.Source, credential.Location})
+	}
+
+	formatter.PrintTable([]string{"Registry", "Username", "Source", "Location"}, data)
+
+	return nil
+}
+
+func runAuthCheck() error {
+
+	credentials, err := listCredentials()
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	data := [][]string{}
+	for _, credential := range credentials {
+		if credential.Hostname == "" {
+			data = append(data, []string{credential.Location, describeUsername(credential), "unknown registry, set " + strings.TrimSuffix(credential.Location, "*") + "REGISTRY"})
+			continue
+		}
+
+		status := checkCredential(credential)
+		if status != credentialValid {
+			failed++
+		}
+		data = append(data, []string{credential.Hostname, describeUsername(credential), status})
+	}
+
+	formatter.PrintTable([]string{"Registry", "Username", "Status"}, data)
+
+	if failed != 0 {
+		return fmt.Errorf("%d of %d credentials are not valid", failed, len(credentials))
+	}
+	return nil
+}
+
+func listCredentials() ([]authapi.CredentialInfo, error) {
+
+	cli, err := auth.NewClientWithOptions(auth.ClientOptions{
+		PullSecrets:              opts.pullSecrets,
+		CredentialProviderConfig: opts.credentialProviderConfig,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return cli.ListCredentials()
+}
+
+// checkCredential pings the registry with the stored credential. A rejected
+// identity token is reported as expired, as that is why refresh tokens stop
+// working, while a rejected password is reported as denied.
+func checkCredential(credential authapi.CredentialInfo) string {
+
+	hostname := registry.RegistryHostname(credential.Hostname)
+	client, err := newRegistryClient(registry.RegistryClientOptions{Hostname: hostname})
+	if err != nil {
+		return err.Error()
+	}
+
+	err = client.Ping(hostname)
+	switch {
+	case err == nil:
+		return credentialValid
+	case registry.IsUnauthorized(err) && credential.IdentityToken:
+		return credentialExpired
+	case registry.IsUnauthorized(err), registry.IsForbidden(err):
+		return credentialDenied
+	}
+
+	return err.Error()
+}
+
+func describeUsername(credential authapi.CredentialInfo) string {
+	if credential.Username == "" && credential.IdentityToken {
+		return "<token>"
+	}
+	return credential.Username
+}
@@ -0,0 +1,184 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/go-units"
+	"github.com/spf13/cobra"
+	"github.com/zawachte-msft/bupkis/pkg/analysis"
+	"github.com/zawachte-msft/bupkis/pkg/filter"
+	"github.com/zawachte-msft/bupkis/pkg/formatter"
+	"github.com/zawachte-msft/bupkis/pkg/registry"
+	"github.com/zawachte-msft/bupkis/pkg/util"
+)
+
+type baseCheckOptions struct {
+	base            string
+	hostname        string
+	baseTags        string
+	previousDigests []string
+	allBaseTags     bool
+	all             bool
+}
+
+var baseCheckOpts = &baseCheckOptions{}
+
+var baseCheckCmd = &cobra.Command{
+	Use:   "base-check <base-ref> [host]",
+	Short: "find images built on an outdated version of a base image",
+	Long: `find images built on an outdated version of a base image.
+
+The tags of the base repository on the same line as the tag of <base-ref>, plus
+any --previous-digest, are the known versions of the base: 22.04 selects 22.04.1,
+jammy selects jammy-20240110. An image whose leading layers match a version other
+than the one <base-ref> points at today needs to be rebuilt. Select the versions
+with a regex in --base-tags instead, or take every tag with --all-base-tags. A
+<base-ref> pinned by digest alone has no line, so only those flags and
+--previous-digest add versions.`,
+	Example: "	bupkis base-check bupkisimages.azurecr.io/base/ubuntu:22.04 --base-tags '^(22\\.04|jammy-)'",
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		baseCheckOpts.base = args[0]
+		if len(args) > 1 {
+			baseCheckOpts.hostname = args[1]
+		}
+
+		return runBaseCheck()
+	},
+}
+
+func init() {
+	baseCheckCmd.Flags().StringVarP(&baseCheckOpts.baseTags, "base-tags", "", "", "regex selecting the tags of the base repository that are versions of the base")
+	baseCheckCmd.Flags().BoolVarP(&baseCheckOpts.allBaseTags, "all-base-tags", "", false, "count every tag of the base repository as a version of the base")
+	baseCheckCmd.Flags().StringArrayVarP(&baseCheckOpts.previousDigests, "previous-digest", "", nil, "digest of an untagged earlier version of the base")
+	baseCheckCmd.Flags().BoolVarP(&baseCheckOpts.all, "all", "a", false, "also list images built on the current base")
+	RootCmd.AddCommand(baseCheckCmd)
+}
+
+func runBaseCheck() error {
+
+	base := util.ParseImageReference(baseCheckOpts.base)
+	if base.Tag == "" && base.Digest == "" {
+		base.Tag = "latest"
+	}
+
+	baseName := fmt.Sprintf("%s/%s:%s", base.Hostname, base.Name, base.Tag)
+	reference := base.Tag
+	if base.Digest != "" {
+		baseName = fmt.Sprintf("%s/%s@%s", base.Hostname, base.Name, base.Digest)
+		reference = base.Digest.String()
+	}
+
+	hostname := baseCheckOpts.hostname
+	if hostname == "" {
+		hostname = base.Hostname
+	}
+
+	client, err := newRegistryClient(registry.RegistryClientOptions{Hostname: base.Hostname})
+	if err != nil {
+		return err
+	}
+
+	current, err := client.GetImageData(base.Hostname, base.Name, reference)
+	if err != nil {
+		return err
+	}
+
+	baseTags, err := client.GetTags(base.Hostname, base.Name)
+	if err != nil {
+		return err
+	}
+	switch {
+	case baseCheckOpts.baseTags != "":
+		match, err := filter.Regex(baseCheckOpts.baseTags)
+		if err != nil {
+			return err
+		}
+		baseTags = filter.Tags(baseTags, match)
+	case baseCheckOpts.allBaseTags:
+	case base.Tag != "":
+		baseTags = filter.Tags(baseTags, filter.VersionLine(base.Tag))
+	default:
+		baseTags = nil
+	}
+
+	baseImages := []registry.ImageData{current}
+	for _, tag := range append(baseTags, baseCheckOpts.previousDigests...) {
+		image, err := client.GetImageData(base.Hostname, base.Name, tag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "WARNING: skipping base version %s of %s/%s: %v\n", tag, base.Hostname, base.Name, err)
+			continue
+		}
+		baseImages = append(baseImages, image)
+	}
+
+	if hostname != base.Hostname {
+		client, err = newRegistryClient(registry.RegistryClientOptions{Hostname: hostname})
+		if err != nil {
+			return err
+		}
+	}
+
+	images, err := client.GetReposByHostName(hostname)
+	if err != nil {
+		return err
+	}
+
+	candidates := []registry.ImageData{}
+	for _, image := range images {
+		if image.Hostname == base.Hostname && image.Name == base.Name {
+			continue
+		}
+		candidates = append(candidates, image)
+	}
+
+	usages := analysis.FindBaseUsage(candidates, analysis.BaseVersions(baseImages))
+	sort.SliceStable(usages, func(i, j int) bool { return usages[i].Base.Created.Before(usages[j].Base.Created) })
+
+	now := time.Now().UTC()
+	data := [][]string{}
+	stale := 0
+	for _, usage := range usages {
+		status := "current"
+		if usage.Base.Digest != current.Digest {
+			status = "stale"
+			stale++
+		} else if !baseCheckOpts.all {
+			continue
+		}
+
+		data = append(data, []string{
+			fmt.Sprintf("%s/%s:%s", usage.Image.Hostname, usage.Image.Name, usage.Image.Tag),
+			status,
+			usage.Base.Digest.String(),
+			strings.Join(usage.Base.Tags, ", "),
+			fmt.Sprintf("%s ago", units.HumanDuration(now.Sub(usage.Base.Created))),
+		})
+	}
+	formatter.PrintTable([]string{"Image", "Status", "Base Digest", "Base Tags", "Base Age"}, data)
+	fmt.Println()
+
+	fmt.Printf("%s is %s, created %s ago. %d of %d images built on a version of it need a rebuild.\n",
+		baseName, current.Digest, units.HumanDuration(now.Sub(current.Created)), stale, len(usages))
+
+	return nil
+}
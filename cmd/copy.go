@@ -0,0 +1,170 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	digest "github.com/opencontainers/go-digest"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/zawachte-msft/bupkis/pkg/layout"
+	"github.com/zawachte-msft/bupkis/pkg/registry"
+)
+
+// imageSource provides the manifests and blobs copyManifest uploads
+type imageSource interface {
+	Manifest(desc v1.Descriptor) (registry.Manifest, error)
+	Blob(desc v1.Descriptor) (io.ReadCloser, error)
+}
+
+// layoutSource reads images from an OCI image layout
+type layoutSource struct {
+	layout *layout.Layout
+}
+
+func (s layoutSource) Manifest(desc v1.Descriptor) (registry.Manifest, error) {
+	content, err := s.layout.ReadBlob(desc.Digest)
+	if err != nil {
+		return registry.Manifest{}, err
+	}
+
+	if digest.FromBytes(content) != desc.Digest {
+		return registry.Manifest{}, fmt.Errorf("manifest %s: content does not match digest", desc.Digest)
+	}
+
+	return registry.Manifest{
+		MediaType: desc.MediaType,
+		Digest:    desc.Digest,
+		Content:   content,
+	}, nil
+}
+
+func (s layoutSource) Blob(desc v1.Descriptor) (io.ReadCloser, error) {
+	return s.layout.OpenBlob(desc.Digest)
+}
+
+// registrySource reads images from a repository of another registry client
+type registrySource struct {
+	client   registry.Client
+	hostname string
+	repo     string
+}
+
+func (s registrySource) Manifest(desc v1.Descriptor) (registry.Manifest, error) {
+	manifest, err := s.client.GetManifest(s.hostname, s.repo, desc.Digest.String())
+	if err != nil {
+		return registry.Manifest{}, err
+	}
+
+	if manifest.Digest != desc.Digest {
+		return registry.Manifest{}, fmt.Errorf("manifest %s: registry returned %s", desc.Digest, manifest.Digest)
+	}
+
+	return manifest, nil
+}
+
+func (s registrySource) Blob(desc v1.Descriptor) (io.ReadCloser, error) {
+	blob, _, err := s.client.GetBlob(s.hostname, s.repo, desc.Digest)
+	return blob, err
+}
+
+type copyOptions struct {
+	chunkSize int64
+	mountFrom []string
+	progress  io.Writer
+}
+
+// copyManifest uploads everything the manifest references before the manifest
+// itself, since registries reject manifests with missing blobs.
+func copyManifest(client registry.Client, imageData registry.ImageData, desc v1.Descriptor, reference string, source imageSource, opts copyOptions) (digest.Digest, error) {
+
+	manifest, err := source.Manifest(desc)
+	if err != nil {
+		return "", err
+	}
+
+	descriptors, err := manifest.References()
+	if err != nil {
+		return "", err
+	}
+
+	for _, child := range descriptors {
+		if manifest.IsIndex() {
+			if _, err := copyManifest(client, imageData, child, child.Digest.String(), source, opts); err != nil {
+				return "", err
+			}
+			continue
+		}
+
+		if registry.IsNonDistributable(child) {
+			fmt.Fprintf(opts.progress, "%s: skipped non-distributable layer\n", shortDigest(child))
+			continue
+		}
+
+		if err := copyBlob(client, imageData, child, source, opts); err != nil {
+			return "", err
+		}
+	}
+
+	return client.PutManifest(imageData.Hostname, imageData.Name, reference, manifest)
+}
+
+func copyBlob(client registry.Client, imageData registry.ImageData, desc v1.Descriptor, source imageSource, opts copyOptions) error {
+
+	exists, err := client.BlobExists(imageData.Hostname, imageData.Name, desc.Digest)
+	if err != nil {
+		return err
+	}
+	if exists {
+		fmt.Fprintf(opts.progress, "%s: already exists\n", shortDigest(desc))
+		return nil
+	}
+
+	for _, fromRepo := range opts.mountFrom {
+		mounted, err := client.MountBlob(imageData.Hostname, imageData.Name, fromRepo, desc.Digest)
+		if err != nil {
+			return err
+		}
+		if mounted {
+			fmt.Fprintf(opts.progress, "%s: mounted from %s\n", shortDigest(desc), fromRepo)
+			return nil
+		}
+	}
+
+	blob, err := source.Blob(desc)
+	if err != nil {
+		return err
+	}
+	defer blob.Close()
+
+	if err := client.UploadBlob(imageData.Hostname, imageData.Name, desc, blob, opts.chunkSize); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(opts.progress, "%s: uploaded\n", shortDigest(desc))
+	return nil
+}
+
+// shortDigest abbreviates a digest for display, like docker shortens image
+// IDs. Digests read from a registry are not trusted to be full length.
+func shortDigest(desc v1.Descriptor) string {
+	encoded := desc.Digest.Encoded()
+	if len(encoded) > 12 {
+		return encoded[:12]
+	}
+	return encoded
+}
@@ -0,0 +1,115 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/zawachte-msft/bupkis/pkg/filter"
+	"github.com/zawachte-msft/bupkis/pkg/registry"
+)
+
+// imageFilterOptions are the filtering flags shared by list and get
+type imageFilterOptions struct {
+	since       string
+	before      string
+	tags        []string
+	excludeTags []string
+	repos       []string
+	newest      int
+}
+
+func (o *imageFilterOptions) addFlags(flags *pflag.FlagSet, withRepos bool) {
+	flags.StringVarP(&o.since, "since", "", "", "only images created after this age or date, e.g. 7d or 2026-01-01")
+	flags.StringVarP(&o.before, "before", "", "", "only images created before this age or date, e.g. 30d or 2026-01-01")
+	flags.StringArrayVarP(&o.tags, "tag", "", nil, "only tags matching this regex")
+	flags.StringArrayVarP(&o.excludeTags, "exclude-tag", "", nil, "leave out tags matching this glob")
+	if withRepos {
+		flags.StringArrayVarP(&o.repos, "repo", "", nil, "only repositories matching this glob, e.g. team-a/*")
+	}
+	flags.IntVarP(&o.newest, "newest", "", 0, "only the newest N tags of each repository")
+}
+
+// apply sets the name filters on the client options, so they run before any manifests are fetched
+func (o *imageFilterOptions) apply(options *registry.RegistryClientOptions) error {
+
+	if len(o.repos) != 0 {
+		matchers := []filter.Matcher{}
+		for _, pattern := range o.repos {
+			match, err := filter.Glob(pattern)
+			if err != nil {
+				return err
+			}
+			matchers = append(matchers, match)
+		}
+		options.RepoFilter = filter.Any(matchers...)
+	}
+
+	matchers := []filter.Matcher{}
+	for _, expr := range o.tags {
+		match, err := filter.Regex(expr)
+		if err != nil {
+			return err
+		}
+		matchers = append(matchers, match)
+	}
+	for _, pattern := range o.excludeTags {
+		match, err := filter.Glob(pattern)
+		if err != nil {
+			return err
+		}
+		matchers = append(matchers, filter.Not(match))
+	}
+	if len(matchers) != 0 {
+		options.TagFilter = filter.All(matchers...)
+	}
+
+	return nil
+}
+
+// needsManifests reports whether any filter is set that can only be applied to fetched images
+func (o *imageFilterOptions) needsManifests() bool {
+	return o.since != "" || o.before != "" || o.newest > 0
+}
+
+// filter applies the filters that need the manifests of the images
+func (o *imageFilterOptions) filter(images []registry.ImageData) ([]registry.ImageData, error) {
+
+	now := time.Now().UTC()
+
+	if o.since != "" {
+		since, err := filter.ParseTime(o.since, now)
+		if err != nil {
+			return nil, err
+		}
+		images = filter.Images(images, filter.CreatedAfter(since))
+	}
+
+	if o.before != "" {
+		before, err := filter.ParseTime(o.before, now)
+		if err != nil {
+			return nil, err
+		}
+		images = filter.Images(images, filter.CreatedBefore(before))
+	}
+
+	if o.newest > 0 {
+		images = filter.Newest(images, o.newest)
+	}
+
+	return images, nil
+}
@@ -17,13 +17,14 @@ package cmd
 
 import (
 	"github.com/spf13/cobra"
-	"github.com/zawachte-msft/bupkis/pkg/formatter"
 	"github.com/zawachte-msft/bupkis/pkg/registry"
 	"github.com/zawachte-msft/bupkis/pkg/util"
 )
 
 type getOptions struct {
-	image string
+	image   string
+	output  imageOutputOptions
+	filters imageFilterOptions
 }
 
 var getOpts = &getOptions{}
@@ -41,21 +42,37 @@ var getCmd = &cobra.Command{
 }
 
 func init() {
+	getOpts.output.addFlags(getCmd.Flags())
+	getOpts.filters.addFlags(getCmd.Flags(), false)
 	RootCmd.AddCommand(getCmd)
 }
 
 func runGet() error {
 
-	imageData := util.ParseImageName(getOpts.image)
+	if err := getOpts.output.validate(); err != nil {
+		return err
+	}
+
+	imageData := util.ParseImageReference(getOpts.image)
+
+	options := registry.RegistryClientOptions{
+		Hostname:     imageData.Hostname,
+		Repository:   imageData.Name,
+		TagMetadata:  !getOpts.output.needsBlobs(),
+		AllPlatforms: getOpts.output.needsBlobs(),
+	}
+	if err := getOpts.filters.apply(&options); err != nil {
+		return err
+	}
 
-	client, err := registry.New(registry.RegistryClientOptions{Hostname: imageData.Hostname})
+	client, err := newRegistryClient(options)
 	if err != nil {
 		return err
 	}
 
 	imagesDatas := []registry.ImageData{}
 
-	if imageData.Tag == "" {
+	if imageData.Tag == "" && imageData.Digest == "" {
 		images, err := client.GetImageDataList(imageData.Hostname, imageData.Name)
 		if err != nil {
 			return err
@@ -63,15 +80,31 @@ func runGet() error {
 
 		imagesDatas = append(imagesDatas, images...)
 	} else {
-		images, err := client.GetImageData(imageData.Hostname, imageData.Name, imageData.Tag)
+		// a digest pins the manifest, any tag next to it is only shown
+		reference := imageData.Tag
+		if imageData.Digest != "" {
+			reference = imageData.Digest.String()
+		}
+
+		images, err := client.GetImageData(imageData.Hostname, imageData.Name, reference)
 		if err != nil {
 			return err
 		}
+		if imageData.Tag != "" {
+			images.Tag = imageData.Tag
+		}
 
 		imagesDatas = append(imagesDatas, images)
 	}
 
-	formatter.PrintOutput(util.ImagesToNestedArray(imagesDatas))
+	imagesDatas, artifacts := getOpts.output.split(imagesDatas)
+
+	imagesDatas, err = getOpts.filters.filter(imagesDatas)
+	if err != nil {
+		return err
+	}
+
+	getOpts.output.print(imagesDatas, artifacts)
 
 	return nil
 }
@@ -0,0 +1,124 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/docker/go-units"
+	"github.com/spf13/cobra"
+	"github.com/zawachte-msft/bupkis/pkg/analysis"
+	"github.com/zawachte-msft/bupkis/pkg/formatter"
+)
+
+type graphOptions struct {
+	hostname     string
+	format       string
+	hideIsolated bool
+	allPlatforms bool
+}
+
+var graphOpts = &graphOptions{}
+
+var graphCmd = &cobra.Command{
+	Use:   "graph [host]",
+	Short: "draw the lineage of images in container registries",
+	Long:  "draw which images are built on which, inferred from the layers they have in common, as a Graphviz DOT or Mermaid graph",
+	Example: "	bupkis graph bupkisimages.azurecr.io --format mermaid",
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 0 {
+			graphOpts.hostname = args[0]
+		}
+
+		return runGraph()
+	},
+}
+
+func init() {
+	graphCmd.Flags().StringVarP(&graphOpts.format, "format", "o", "dot", "graph format, dot or mermaid")
+	graphCmd.Flags().BoolVarP(&graphOpts.hideIsolated, "hide-isolated", "", false, "leave out images that share no layers with another image")
+	graphCmd.Flags().BoolVarP(&graphOpts.allPlatforms, "all-platforms", "", false, "read every platform of multi-platform images instead of only the default one")
+	RootCmd.AddCommand(graphCmd)
+}
+
+func runGraph() error {
+
+	if graphOpts.format != "dot" && graphOpts.format != "mermaid" {
+		return fmt.Errorf("unknown graph format %q", graphOpts.format)
+	}
+
+	images, err := getAllImages(graphOpts.hostname, graphOpts.allPlatforms)
+	if err != nil {
+		return err
+	}
+
+	lineage := analysis.BuildLineage(images)
+
+	connected := map[int]bool{}
+	for _, edge := range lineage.Edges {
+		connected[edge.Parent] = true
+		connected[edge.Child] = true
+	}
+
+	// the hostname is noise when every image comes from the same registry
+	hostnames := map[string]bool{}
+	for _, node := range lineage.Nodes {
+		hostnames[node.Hostname] = true
+	}
+
+	now := time.Now().UTC()
+	graph := formatter.Graph{}
+	for i, node := range lineage.Nodes {
+		if graphOpts.hideIsolated && !connected[i] {
+			continue
+		}
+
+		name := node.Name
+		if len(hostnames) > 1 {
+			name = fmt.Sprintf("%s/%s", node.Hostname, node.Name)
+		}
+
+		graph.Nodes = append(graph.Nodes, formatter.GraphNode{
+			ID:    fmt.Sprintf("n%d", i),
+			Label: fmt.Sprintf("%s:%s\n%s, %s ago", name, strings.Join(node.Tags, ", "), units.HumanSize(float64(node.Size)), units.HumanDuration(now.Sub(node.Created))),
+		})
+	}
+
+	for _, edge := range lineage.Edges {
+		label := fmt.Sprintf("%d shared layers", edge.SharedLayers)
+		if edge.SharedLayers == 1 {
+			label = "1 shared layer"
+		}
+
+		graph.Edges = append(graph.Edges, formatter.GraphEdge{
+			From:  fmt.Sprintf("n%d", edge.Parent),
+			To:    fmt.Sprintf("n%d", edge.Child),
+			Label: label,
+		})
+	}
+
+	if graphOpts.format == "mermaid" {
+		formatter.WriteMermaid(os.Stdout, graph)
+	} else {
+		formatter.WriteDOT(os.Stdout, graph)
+	}
+
+	return nil
+}
@@ -0,0 +1,77 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/zawachte-msft/bupkis/pkg/filter"
+	"github.com/zawachte-msft/bupkis/pkg/registry"
+	"github.com/zawachte-msft/bupkis/pkg/util"
+)
+
+type latestOptions struct {
+	image             string
+	constraint        string
+	includePrerelease bool
+}
+
+var latestOpts = &latestOptions{}
+
+var latestCmd = &cobra.Command{
+	Use:   "latest <host/repo>",
+	Short: "print the highest semantic version tag of a repository",
+	Long:  "print the highest tag of a repository that is a semantic version, optionally satisfying a constraint. Tags that are not semantic versions are ignored.",
+	Example: "	bupkis latest bupkisimages.azurecr.io/docs-image --constraint '^1.4'",
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		latestOpts.image = args[0]
+		return runLatest()
+	},
+}
+
+func init() {
+	latestCmd.Flags().StringVarP(&latestOpts.constraint, "constraint", "c", "", "semver constraint the tag has to satisfy, e.g. '^1.4' or '>= 2, < 3'")
+	latestCmd.Flags().BoolVarP(&latestOpts.includePrerelease, "include-prerelease", "", false, "consider pre-release versions such as 2.0.0-rc.1")
+	RootCmd.AddCommand(latestCmd)
+}
+
+func runLatest() error {
+
+	imageData := util.ParseImageReference(latestOpts.image)
+
+	client, err := newRegistryClient(registry.RegistryClientOptions{Hostname: imageData.Hostname, Repository: imageData.Name})
+	if err != nil {
+		return err
+	}
+
+	tags, err := client.GetTags(imageData.Hostname, imageData.Name)
+	if err != nil {
+		return err
+	}
+
+	latest, ok, err := filter.Latest(tags, latestOpts.constraint, latestOpts.includePrerelease)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("no tag of %s/%s is a semantic version matching %q", imageData.Hostname, imageData.Name, latestOpts.constraint)
+	}
+
+	fmt.Println(latest)
+	return nil
+}
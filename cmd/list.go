@@ -16,14 +16,27 @@ limitations under the License.
 package cmd
 
 import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/docker/go-units"
 	"github.com/spf13/cobra"
+	"github.com/zawachte-msft/bupkis/pkg/analysis"
 	"github.com/zawachte-msft/bupkis/pkg/formatter"
 	"github.com/zawachte-msft/bupkis/pkg/registry"
-	"github.com/zawachte-msft/bupkis/pkg/util"
 )
 
 type listOptions struct {
-	hostname string
+	hostname   string
+	repos      bool
+	withLatest bool
+	tree       bool
+	showTags   bool
+	output     imageOutputOptions
+	filters    imageFilterOptions
 }
 
 var listOpts = &listOptions{}
@@ -45,22 +58,229 @@ var listCmd = &cobra.Command{
 
 func init() {
 	listCmd.Flags().StringVarP(&listOpts.hostname, "hostname", "n", "", "registry hostname")
+	listCmd.Flags().BoolVarP(&listOpts.repos, "repos", "", false, "list repositories and their tag counts without fetching any manifests")
+	listCmd.Flags().BoolVarP(&listOpts.withLatest, "with-latest", "", false, "with --repos, show the most recently created tag of each repository, which fetches the manifest of every tag")
+	listCmd.Flags().BoolVarP(&listOpts.tree, "tree", "", false, "show the repositories as a tree of their namespace paths")
+	listCmd.Flags().BoolVarP(&listOpts.showTags, "show-tags", "", false, "with --tree, show the tags of each repository")
+	listOpts.output.addFlags(listCmd.Flags())
+	listOpts.filters.addFlags(listCmd.Flags(), true)
 	RootCmd.AddCommand(listCmd)
 }
 
 func runList() error {
 
-	client, err := registry.New(registry.RegistryClientOptions{Hostname: listOpts.hostname})
+	if err := listOpts.output.validate(); err != nil {
+		return err
+	}
+
+	if listOpts.withLatest && (!listOpts.repos || listOpts.tree) {
+		return fmt.Errorf("--with-latest can only be used with --repos")
+	}
+	if listOpts.showTags && !listOpts.tree {
+		return fmt.Errorf("--show-tags can only be used with --tree")
+	}
+	if listOpts.tree && listOpts.output.groupBy != "" {
+		return fmt.Errorf("--group-by cannot be used with --tree")
+	}
+
+	options := registry.RegistryClientOptions{
+		Hostname:     listOpts.hostname,
+		TagMetadata:  !listOpts.tree && !listOpts.output.needsBlobs(),
+		AllPlatforms: listOpts.tree || listOpts.output.needsBlobs(),
+	}
+	if err := listOpts.filters.apply(&options); err != nil {
+		return err
+	}
+
+	client, err := newRegistryClient(options)
 	if err != nil {
 		return err
 	}
 
+	if listOpts.repos {
+		if listOpts.output.groupBy != "" {
+			return fmt.Errorf("--group-by cannot be used with --repos")
+		}
+		if listOpts.filters.needsManifests() {
+			return fmt.Errorf("--since, --before and --newest need the manifests of every tag and cannot be used with --repos")
+		}
+		if listOpts.tree {
+			return runListRepoTree(client)
+		}
+		return runListRepos(client)
+	}
+
 	images, err := client.GetRepos()
 	if err != nil {
 		return err
 	}
 
-	formatter.PrintOutput(util.ImagesToNestedArray(images))
+	images, artifacts := listOpts.output.split(images)
+
+	images, err = listOpts.filters.filter(images)
+	if err != nil {
+		return err
+	}
+
+	if listOpts.tree {
+		printTree(analysis.BuildTree(images), true)
+		return nil
+	}
+
+	listOpts.output.print(images, artifacts)
+
+	return nil
+}
+
+func runListRepos(client registry.Client) error {
+
+	repos, err := client.GetRepoList()
+	if err != nil {
+		return err
+	}
+	repos = listOpts.output.filterTags(repos)
+
+	sort.Slice(repos, func(i, j int) bool {
+		if repos[i].Hostname != repos[j].Hostname {
+			return repos[i].Hostname < repos[j].Hostname
+		}
+		return repos[i].Name < repos[j].Name
+	})
+
+	// registries listed through the API of their vendor also tell when a
+	// repository was pushed to and how often it was pulled
+	withActivity := false
+	for _, repo := range repos {
+		if !repo.PushedAt.IsZero() || repo.PullCount >= 0 {
+			withActivity = true
+		}
+	}
+
+	header := []string{"Name", "Tags"}
+	if withActivity {
+		header = append(header, "Pushed", "Pulls")
+	}
+	if listOpts.withLatest {
+		header = append(header, "Latest", "Created")
+	}
+
+	now := time.Now().UTC()
+	data := [][]string{}
+	for _, repo := range repos {
+		row := []string{fmt.Sprintf("%s/%s", repo.Hostname, repo.Name), strconv.Itoa(len(repo.Tags))}
+
+		if withActivity {
+			pulls := ""
+			if repo.PullCount >= 0 {
+				pulls = strconv.FormatInt(repo.PullCount, 10)
+			}
+			row = append(row, formatAge(repo.PushedAt, now), pulls)
+		}
+		if listOpts.withLatest {
+			row = append(row, describeLatestTag(client, repo)...)
+		}
+
+		data = append(data, row)
+	}
+
+	formatter.PrintTable(header, data)
+
+	return nil
+}
+
+func runListRepoTree(client registry.Client) error {
+
+	repos, err := client.GetRepoList()
+	if err != nil {
+		return err
+	}
+	repos = listOpts.output.filterTags(repos)
+
+	printTree(analysis.BuildRepoTree(repos), false)
 
 	return nil
 }
+
+// printTree prints the namespace tree with the tag count of every node, and
+// the sizes when the tree was built from the manifests.
+func printTree(roots []*analysis.TreeNode, withSizes bool) {
+
+	header := []string{"Name", "Tags"}
+	if withSizes {
+		header = append(header, "Size")
+	}
+
+	items := []formatter.TreeItem{}
+	for _, root := range roots {
+		items = append(items, treeItem(root, withSizes))
+	}
+
+	formatter.PrintTree(header, items)
+}
+
+func treeItem(node *analysis.TreeNode, withSizes bool) formatter.TreeItem {
+
+	item := formatter.TreeItem{
+		Name:    node.Name,
+		Columns: []string{strconv.Itoa(node.TagCount)},
+	}
+	if withSizes {
+		item.Columns = append(item.Columns, units.HumanSize(float64(node.Size)))
+	}
+
+	for _, child := range node.Children {
+		item.Children = append(item.Children, treeItem(child, withSizes))
+	}
+
+	if !listOpts.showTags {
+		return item
+	}
+
+	if withSizes {
+		for _, image := range node.Images {
+			item.Children = append(item.Children, formatter.TreeItem{
+				Name:    ":" + image.Tag,
+				Columns: []string{"", units.HumanSize(float64(image.Size))},
+			})
+		}
+		return item
+	}
+
+	for _, tag := range node.Tags {
+		item.Children = append(item.Children, formatter.TreeItem{Name: ":" + tag, Columns: []string{""}})
+	}
+
+	return item
+}
+
+// describeLatestTag finds the most recently created tag of the repository.
+// It describes every tag, from the registry metadata when it can.
+func describeLatestTag(client registry.Client, repo registry.RepoData) []string {
+
+	if len(repo.Tags) == 0 {
+		return []string{"", ""}
+	}
+
+	images, err := client.GetImageDataList(repo.Hostname, repo.Name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: finding the latest tag of %s/%s: %v\n", repo.Hostname, repo.Name, err)
+		return []string{"", ""}
+	}
+
+	listed := map[string]bool{}
+	for _, tag := range repo.Tags {
+		listed[tag] = true
+	}
+
+	latest := registry.ImageData{}
+	for _, image := range images {
+		if listed[image.Tag] && image.Created.After(latest.Created) {
+			latest = image
+		}
+	}
+	if latest.Created.IsZero() {
+		return []string{"", ""}
+	}
+
+	return []string{latest.Tag, fmt.Sprintf("%s ago", units.HumanDuration(time.Now().UTC().Sub(latest.Created)))}
+}
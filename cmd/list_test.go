@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/zawachte-msft/bupkis/pkg/registry"
+)
+
+// fakeListClient describes the tags of one repository from memory
+type fakeListClient struct {
+	registry.Client
+	images []registry.ImageData
+	err    error
+}
+
+func (c *fakeListClient) GetImageDataList(hostname string, repo string) ([]registry.ImageData, error) {
+	return c.images, c.err
+}
+
+func TestDescribeLatestTag(t *testing.T) {
+
+	now := time.Now().UTC()
+	client := &fakeListClient{images: []registry.ImageData{
+		{Tag: "1.0", Created: now.Add(-72 * time.Hour)},
+		{Tag: "2.0", Created: now.Add(-48 * time.Hour)},
+		{Tag: "rc", Created: now.Add(-time.Hour)},
+		{Tag: "sbom"},
+	}}
+
+	// rc is newer, but was left out of the listed tags
+	repo := registry.RepoData{Hostname: "registry.example.com", Name: "app", Tags: []string{"1.0", "2.0", "sbom"}}
+	got := describeLatestTag(client, repo)
+	if got[0] != "2.0" || got[1] != "2 days ago" {
+		t.Errorf("describeLatestTag() = %v, want 2.0 created 2 days ago", got)
+	}
+
+	repo.Tags = []string{"sbom"}
+	if got := describeLatestTag(client, repo); got[0] != "" || got[1] != "" {
+		t.Errorf("describeLatestTag() = %v for tags without a creation time, want nothing", got)
+	}
+
+	client.err = fmt.Errorf("unavailable")
+	repo.Tags = []string{"1.0"}
+	if got := describeLatestTag(client, repo); got[0] != "" || got[1] != "" {
+		t.Errorf("describeLatestTag() = %v when the tags cannot be described, want nothing", got)
+	}
+}
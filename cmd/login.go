@@ -16,9 +16,16 @@ limitations under the License.
 package cmd
 
 import (
+	"bufio"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
 
+	"github.com/docker/docker/pkg/term"
 	"github.com/spf13/cobra"
 	auth "github.com/zawachte-msft/bupkis/pkg/auth/docker"
 )
@@ -29,6 +36,7 @@ type loginOptions struct {
 	username  string
 	password  string
 	fromStdin bool
+	helper    string
 }
 
 var loginOpts = &loginOptions{}
@@ -49,13 +57,18 @@ func init() {
 	loginCmd.Flags().StringVarP(&loginOpts.username, "username", "u", "", "registry username")
 	loginCmd.Flags().StringVarP(&loginOpts.password, "password", "p", "", "registry password or identity token")
 	loginCmd.Flags().BoolVarP(&loginOpts.fromStdin, "password-stdin", "", false, "read password or identity token from stdin")
+	loginCmd.Flags().StringVarP(&loginOpts.helper, "credential-helper", "", "", "store the credential with docker-credential-<helper> instead of the configured store")
 	RootCmd.AddCommand(loginCmd)
 }
 
 func runLogin() error {
 
+	if err := readLoginSecret(); err != nil {
+		return err
+	}
+
 	// Prepare auth client
-	cli, err := auth.NewClient()
+	cli, err := auth.NewClientWithOptions(auth.ClientOptions{CredentialHelper: loginOpts.helper})
 	if err != nil {
 		return err
 	}
@@ -67,3 +80,80 @@ func runLogin() error {
 	fmt.Println("Login Succeeded")
 	return nil
 }
+
+// readLoginSecret fills in the password from stdin, or prompts for the
+// missing username and password on a terminal.
+func readLoginSecret() error {
+
+	if loginOpts.fromStdin {
+		if loginOpts.password != "" {
+			return errors.New("--password and --password-stdin are mutually exclusive")
+		}
+
+		password, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		loginOpts.password = strings.TrimSuffix(strings.TrimSuffix(string(password), "\n"), "\r")
+		if loginOpts.password == "" {
+			return errors.New("no password or identity token on stdin")
+		}
+		return nil
+	}
+
+	if loginOpts.password != "" {
+		fmt.Fprintln(os.Stderr, "WARNING! Using --password via the CLI is insecure. Use --password-stdin.")
+		return nil
+	}
+
+	fd, isTerminal := term.GetFdInfo(os.Stdin)
+	if !isTerminal {
+		return errors.New("no password given, use --password-stdin when stdin is not a terminal")
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+
+	if loginOpts.username == "" {
+		fmt.Fprint(os.Stderr, "Username (leave empty to use an identity token): ")
+		username, err := readLine(reader)
+		if err != nil {
+			return err
+		}
+		loginOpts.username = username
+	}
+
+	if loginOpts.username == "" {
+		fmt.Fprint(os.Stderr, "Token: ")
+	} else {
+		fmt.Fprint(os.Stderr, "Password: ")
+	}
+
+	state, err := term.SaveState(fd)
+	if err != nil {
+		return err
+	}
+	if err := term.DisableEcho(fd, state); err != nil {
+		return err
+	}
+	password, err := readLine(reader)
+	term.RestoreTerminal(fd, state)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return err
+	}
+
+	if password == "" {
+		return errors.New("password or identity token required")
+	}
+	loginOpts.password = password
+
+	return nil
+}
+
+func readLine(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil && !(err == io.EOF && line != "") {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
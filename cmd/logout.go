@@ -0,0 +1,62 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	auth "github.com/zawachte-msft/bupkis/pkg/auth/docker"
+)
+
+type logoutOptions struct {
+	hostname string
+}
+
+var logoutOpts = &logoutOptions{}
+
+var logoutCmd = &cobra.Command{
+	Use:   "logout",
+	Short: "logout from a container registry",
+	Long:  "logout from a container registry by removing its credential from the configured store",
+	Example: "	bupkis logout cr.io",
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		logoutOpts.hostname = args[0]
+		return runLogout()
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(logoutCmd)
+}
+
+func runLogout() error {
+
+	// Prepare auth client
+	cli, err := auth.NewClient()
+	if err != nil {
+		return err
+	}
+
+	// Logout
+	if err := cli.Logout(context.Background(), logoutOpts.hostname); err != nil {
+		return err
+	}
+	fmt.Printf("Removing login credentials for %s\n", logoutOpts.hostname)
+	return nil
+}
@@ -0,0 +1,204 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/go-units"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/spf13/pflag"
+	"github.com/zawachte-msft/bupkis/pkg/analysis"
+	"github.com/zawachte-msft/bupkis/pkg/formatter"
+	"github.com/zawachte-msft/bupkis/pkg/registry"
+	"github.com/zawachte-msft/bupkis/pkg/util"
+)
+
+const (
+	groupByDigest = "digest"
+	groupByRepo   = "repo"
+)
+
+// artifactIndicators describe an image by the kinds of artifacts attached to it, in display order
+var artifactIndicators = []struct {
+	kind      string
+	indicator string
+}{
+	{registry.ArtifactSignature, "signed"},
+	{registry.ArtifactAttestation, "attested"},
+	{registry.ArtifactSBOM, "sbom"},
+	{registry.ArtifactOther, "artifacts"},
+}
+
+// imageOutputOptions are the output flags shared by list and get
+type imageOutputOptions struct {
+	groupBy       string
+	showArtifacts bool
+}
+
+func (o *imageOutputOptions) addFlags(flags *pflag.FlagSet) {
+	flags.StringVarP(&o.groupBy, "group-by", "", "", "group the tags by digest, or summarize them by repo")
+	flags.BoolVarP(&o.showArtifacts, "show-artifacts", "", false, "also list signature, attestation and SBOM tags")
+}
+
+func (o *imageOutputOptions) validate() error {
+	switch o.groupBy {
+	case "", groupByDigest, groupByRepo:
+		return nil
+	}
+	return fmt.Errorf("unsupported --group-by %q, use %s or %s", o.groupBy, groupByDigest, groupByRepo)
+}
+
+// needsBlobs reports whether the output counts the blobs of the images,
+// which describing tags from registry metadata leaves out
+func (o *imageOutputOptions) needsBlobs() bool {
+	return o.groupBy == groupByRepo
+}
+
+// split returns the images to list and the artifacts attached to them.
+// Artifacts are only listed themselves with --show-artifacts.
+func (o *imageOutputOptions) split(images []registry.ImageData) ([]registry.ImageData, []registry.ImageData) {
+
+	withoutArtifacts, artifacts := analysis.SplitArtifacts(images)
+	if o.showArtifacts {
+		return images, artifacts
+	}
+
+	return withoutArtifacts, artifacts
+}
+
+// filterTags leaves out the tags named after the artifact conventions, for
+// listings that do not fetch the manifests, unless --show-artifacts is set.
+func (o *imageOutputOptions) filterTags(repos []registry.RepoData) []registry.RepoData {
+
+	if o.showArtifacts {
+		return repos
+	}
+
+	returnRepos := []registry.RepoData{}
+	for _, repo := range repos {
+		tags := []string{}
+		for _, tag := range repo.Tags {
+			if _, _, ok := registry.ParseArtifactTag(tag); !ok {
+				tags = append(tags, tag)
+			}
+		}
+		repo.Tags = tags
+		returnRepos = append(returnRepos, repo)
+	}
+
+	return returnRepos
+}
+
+// print prints one row per tag, or the tags grouped by digest or repository.
+// Images with signatures, attestations or SBOMs are marked as such.
+func (o *imageOutputOptions) print(images []registry.ImageData, artifacts []registry.ImageData) {
+
+	now := time.Now().UTC()
+	kinds := analysis.ArtifactKinds(artifacts)
+
+	switch o.groupBy {
+	case groupByDigest:
+		header := []string{"Name", "Tags", "Digest", "Created", "Size"}
+		if len(artifacts) != 0 {
+			header = append(header, "Artifacts")
+		}
+
+		data := [][]string{}
+		for _, group := range analysis.GroupByDigest(images) {
+			row := []string{
+				fmt.Sprintf("%s/%s", group.Hostname, group.Name),
+				strings.Join(group.Tags, ", "),
+				shortDigest(v1.Descriptor{Digest: group.Digest}),
+				formatAge(group.Created, now),
+				units.HumanSize(float64(group.Size)),
+			}
+			if len(artifacts) != 0 {
+				row = append(row, describeArtifacts(group.Image, kinds))
+			}
+			data = append(data, row)
+		}
+		formatter.PrintTable(header, data)
+
+	case groupByRepo:
+		data := [][]string{}
+		for _, stats := range analysis.RepoStatistics(images, now) {
+			data = append(data, []string{
+				fmt.Sprintf("%s/%s", stats.Hostname, stats.Name),
+				strconv.Itoa(stats.Tags),
+				stats.Newest.Tag,
+				formatAge(stats.Newest.Created, now),
+				units.HumanSize(float64(stats.UniqueSize)),
+			})
+		}
+		formatter.PrintTable([]string{"Name", "Tags", "Newest", "Created", "Size"}, data)
+
+	default:
+		if len(artifacts) == 0 {
+			formatter.PrintOutput(util.ImagesToNestedArray(images))
+			return
+		}
+
+		data := [][]string{}
+		for _, image := range images {
+			if image.Created.IsZero() && !image.IsArtifact() {
+				continue
+			}
+			data = append(data, []string{
+				fmt.Sprintf("%s/%s", image.Hostname, image.Name),
+				image.Tag,
+				formatAge(image.Created, now),
+				describeArtifacts(image, kinds),
+			})
+		}
+		formatter.PrintTable([]string{"Name", "Tag", "Created", "Artifacts"}, data)
+	}
+}
+
+// describeArtifacts names the kind of an artifact, or the kinds of artifacts attached to an image
+func describeArtifacts(image registry.ImageData, kinds map[string][]string) string {
+
+	if image.IsArtifact() {
+		if image.Subject == "" {
+			return image.ArtifactKind
+		}
+		return fmt.Sprintf("%s of %s", image.ArtifactKind, shortDigest(v1.Descriptor{Digest: image.Subject}))
+	}
+
+	attached := map[string]bool{}
+	for _, kind := range kinds[analysis.ArtifactKey(image)] {
+		attached[kind] = true
+	}
+
+	indicators := []string{}
+	for _, artifactIndicator := range artifactIndicators {
+		if attached[artifactIndicator.kind] {
+			indicators = append(indicators, artifactIndicator.indicator)
+		}
+	}
+
+	return strings.Join(indicators, ", ")
+}
+
+func formatAge(created time.Time, now time.Time) string {
+	if created.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf("%s ago", units.HumanDuration(now.Sub(created)))
+}
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	digest "github.com/opencontainers/go-digest"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+func TestFormatAge(t *testing.T) {
+
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	if got := formatAge(now.Add(-3*24*time.Hour), now); got != "3 days ago" {
+		t.Errorf("formatAge() = %q, want 3 days ago", got)
+	}
+	if got := formatAge(time.Time{}, now); got != "" {
+		t.Errorf("formatAge() = %q for an unknown creation time, want nothing", got)
+	}
+}
+
+func TestShortDigest(t *testing.T) {
+
+	dgst := digest.FromString("app")
+	if got := shortDigest(v1.Descriptor{Digest: dgst}); got != dgst.Encoded()[:12] {
+		t.Errorf("shortDigest(%s) = %q", dgst, got)
+	}
+	// digests from a registry may be malformed and shorter than 12 characters
+	if got := shortDigest(v1.Descriptor{Digest: "sha256:abc"}); got != "abc" {
+		t.Errorf("shortDigest(sha256:abc) = %q, want abc", got)
+	}
+}
@@ -0,0 +1,146 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/zawachte-msft/bupkis/pkg/layout"
+	"github.com/zawachte-msft/bupkis/pkg/registry"
+	"github.com/zawachte-msft/bupkis/pkg/util"
+)
+
+type pullOptions struct {
+	image     string
+	ociLayout string
+}
+
+var pullOpts = &pullOptions{}
+
+var pullCmd = &cobra.Command{
+	Use:   "pull",
+	Short: "pull an image into an OCI image layout",
+	Long:  "pull an image into an OCI image layout directory without a container runtime. Blobs already in the layout are verified and skipped.",
+	Example: "	bupkis pull bupkisimages.azurecr.io/docs-image:latest --oci-layout ./docs-image",
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pullOpts.image = args[0]
+		return runPull()
+	},
+}
+
+func init() {
+	pullCmd.Flags().StringVarP(&pullOpts.ociLayout, "oci-layout", "", "", "directory of the OCI image layout to write")
+	pullCmd.MarkFlagRequired("oci-layout")
+	RootCmd.AddCommand(pullCmd)
+}
+
+func runPull() error {
+
+	imageData := util.ParseImageReference(pullOpts.image)
+
+	reference := imageData.Tag
+	if imageData.Digest != "" {
+		reference = imageData.Digest.String()
+	}
+	if reference == "" {
+		reference = "latest"
+		imageData.Tag = reference
+	}
+
+	client, err := newRegistryClient(registry.RegistryClientOptions{Hostname: imageData.Hostname, Repository: imageData.Name})
+	if err != nil {
+		return err
+	}
+
+	ociLayout, err := layout.New(pullOpts.ociLayout)
+	if err != nil {
+		return err
+	}
+
+	manifest, err := client.GetManifest(imageData.Hostname, imageData.Name, reference)
+	if err != nil {
+		return err
+	}
+
+	if err := pullManifest(client, imageData, manifest, ociLayout); err != nil {
+		return err
+	}
+
+	if err := ociLayout.AddToIndex(manifest.Descriptor(), imageData.Tag); err != nil {
+		return err
+	}
+
+	fmt.Printf("Pulled %s/%s@%s into %s\n", imageData.Hostname, imageData.Name, manifest.Digest, pullOpts.ociLayout)
+	return nil
+}
+
+// pullManifest stores everything the manifest references before the manifest
+// itself, so a manifest in the layout always has its blobs present.
+func pullManifest(client registry.Client, imageData registry.ImageData, manifest registry.Manifest, ociLayout *layout.Layout) error {
+
+	descriptors, err := manifest.References()
+	if err != nil {
+		return err
+	}
+
+	for _, desc := range descriptors {
+		if manifest.IsIndex() {
+			child, err := client.GetManifest(imageData.Hostname, imageData.Name, desc.Digest.String())
+			if err != nil {
+				return err
+			}
+			if child.Digest != desc.Digest {
+				return fmt.Errorf("manifest %s: registry returned %s", desc.Digest, child.Digest)
+			}
+
+			if err := pullManifest(client, imageData, child, ociLayout); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if registry.IsNonDistributable(desc) {
+			fmt.Printf("%s: skipped non-distributable layer\n", shortDigest(desc))
+			continue
+		}
+
+		exists, err := ociLayout.HasBlob(desc)
+		if err != nil {
+			return err
+		}
+		if exists {
+			fmt.Printf("%s: already exists\n", shortDigest(desc))
+			continue
+		}
+
+		blob, _, err := client.GetBlob(imageData.Hostname, imageData.Name, desc.Digest)
+		if err != nil {
+			return err
+		}
+
+		err = ociLayout.WriteBlob(desc, blob)
+		blob.Close()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s: downloaded\n", shortDigest(desc))
+	}
+
+	return ociLayout.WriteBlob(manifest.Descriptor(), bytes.NewReader(manifest.Content))
+}
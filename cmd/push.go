@@ -0,0 +1,198 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/docker/go-units"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/spf13/cobra"
+	"github.com/zawachte-msft/bupkis/pkg/layout"
+	"github.com/zawachte-msft/bupkis/pkg/registry"
+	"github.com/zawachte-msft/bupkis/pkg/util"
+)
+
+type pushOptions struct {
+	source    string
+	image     string
+	ref       string
+	mountFrom []string
+	chunkSize string
+}
+
+var pushOpts = &pushOptions{}
+
+var pushCmd = &cobra.Command{
+	Use:   "push <oci-layout-dir|docker-archive.tar> <dst-ref>",
+	Short: "push an image from an OCI image layout or docker save tarball",
+	Long:  "push an image from an OCI image layout directory or a docker save tarball without a container runtime. Blobs the registry already has are skipped.",
+	Example: "	bupkis push ./docs-image bupkisimages.azurecr.io/docs-image:latest",
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pushOpts.source = args[0]
+		pushOpts.image = args[1]
+		return runPush()
+	},
+}
+
+func init() {
+	pushCmd.Flags().StringVarP(&pushOpts.ref, "ref", "", "", "ref name of the image to push when the source holds several")
+	pushCmd.Flags().StringArrayVarP(&pushOpts.mountFrom, "mount-from", "", nil, "repository on the same registry to mount existing blobs from")
+	pushCmd.Flags().StringVarP(&pushOpts.chunkSize, "chunk-size", "", "", "upload blobs in chunks of this size, e.g. 10MB (default uploads each blob in one request)")
+	RootCmd.AddCommand(pushCmd)
+}
+
+func runPush() error {
+
+	imageData := util.ParseImageReference(pushOpts.image)
+
+	chunkSize := int64(0)
+	if pushOpts.chunkSize != "" {
+		size, err := units.FromHumanSize(pushOpts.chunkSize)
+		if err != nil {
+			return err
+		}
+		chunkSize = size
+	}
+
+	source, cleanup, err := openPushSource(pushOpts.source)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	index, err := source.Index()
+	if err != nil {
+		return err
+	}
+
+	desc, err := selectManifest(index, pushOpts.ref, imageData.Tag)
+	if err != nil {
+		return err
+	}
+
+	if imageData.Tag == "" {
+		imageData.Tag = refNameTag(desc.Annotations[v1.AnnotationRefName])
+	}
+	if imageData.Tag == "" {
+		imageData.Tag = "latest"
+	}
+
+	client, err := newRegistryClient(registry.RegistryClientOptions{Hostname: imageData.Hostname, Repository: imageData.Name})
+	if err != nil {
+		return err
+	}
+
+	dgst, err := copyManifest(client, imageData, desc, imageData.Tag, layoutSource{layout: source}, copyOptions{
+		chunkSize: chunkSize,
+		mountFrom: pushOpts.mountFrom,
+		progress:  os.Stdout,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Pushed %s/%s:%s@%s\n", imageData.Hostname, imageData.Name, imageData.Tag, dgst)
+	return nil
+}
+
+// openPushSource opens an OCI image layout directly, or converts a docker save
+// tarball into a temporary layout that is removed by the returned cleanup.
+func openPushSource(sourcePath string) (*layout.Layout, func(), error) {
+
+	info, err := os.Stat(sourcePath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if info.IsDir() {
+		source, err := layout.Open(sourcePath)
+		return source, func() {}, err
+	}
+
+	dir, err := ioutil.TempDir("", "bupkis-push")
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+
+	source, err := layout.New(dir)
+	if err == nil {
+		err = layout.ImportDockerArchive(sourcePath, source)
+	}
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+
+	return source, cleanup, nil
+}
+
+// selectManifest picks the manifest to push from the index of the source:
+// the one named by ref, the only one, or the one named like the destination
+// tag. Images imported from docker save are named repo:tag, and are also
+// found by their tag alone when no other repository has it.
+func selectManifest(index v1.Index, ref string, tag string) (v1.Descriptor, error) {
+
+	if ref == "" && len(index.Manifests) == 1 {
+		return index.Manifests[0], nil
+	}
+	if ref == "" {
+		ref = tag
+	}
+
+	refNames := []string{}
+	tagged := []v1.Descriptor{}
+	for _, desc := range index.Manifests {
+		refName := desc.Annotations[v1.AnnotationRefName]
+		if ref != "" && refName == ref {
+			return desc, nil
+		}
+		if refName != "" {
+			refNames = append(refNames, refName)
+		}
+		if ref != "" && refName != ref && refNameTag(refName) == ref {
+			tagged = append(tagged, desc)
+		}
+	}
+	if len(tagged) == 1 {
+		return tagged[0], nil
+	}
+	if len(tagged) > 1 {
+		return v1.Descriptor{}, fmt.Errorf("source contains several images tagged %q, select one with --ref (available: %s)", ref, strings.Join(refNames, ", "))
+	}
+
+	if len(index.Manifests) == 0 {
+		return v1.Descriptor{}, fmt.Errorf("source contains no images")
+	}
+	if ref == "" {
+		return v1.Descriptor{}, fmt.Errorf("source contains %d images, select one with --ref (available: %s)", len(index.Manifests), strings.Join(refNames, ", "))
+	}
+	return v1.Descriptor{}, fmt.Errorf("source contains no image named %q (available: %s)", ref, strings.Join(refNames, ", "))
+}
+
+// refNameTag returns the tag of a repo:tag ref name, or the ref name itself when it is a plain tag
+func refNameTag(refName string) string {
+	lastSegment := refName[strings.LastIndex(refName, "/")+1:]
+	if i := strings.LastIndex(lastSegment, ":"); i != -1 {
+		return lastSegment[i+1:]
+	}
+	return refName
+}
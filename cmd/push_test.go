@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"testing"
+
+	digest "github.com/opencontainers/go-digest"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+func TestSelectManifest(t *testing.T) {
+
+	named := func(refName string) v1.Descriptor {
+		return v1.Descriptor{
+			Digest:      digest.FromString(refName),
+			Annotations: map[string]string{v1.AnnotationRefName: refName},
+		}
+	}
+	index := v1.Index{Manifests: []v1.Descriptor{
+		named("alpine:3.18"),
+		named("nginx:3.18"),
+		named("nginx:latest"),
+		named("1.0"),
+	}}
+
+	tests := []struct {
+		ref  string
+		tag  string
+		want string
+	}{
+		{"nginx:3.18", "", "nginx:3.18"},
+		{"", "nginx:3.18", "nginx:3.18"},
+		{"", "latest", "nginx:latest"},
+		{"1.0", "", "1.0"},
+		{"", "3.18", ""},
+		{"", "", ""},
+		{"busybox:1", "", ""},
+	}
+
+	for _, test := range tests {
+		desc, err := selectManifest(index, test.ref, test.tag)
+		got := desc.Annotations[v1.AnnotationRefName]
+		if test.want == "" && err == nil {
+			t.Errorf("selectManifest(%q, %q) = %q, want an error", test.ref, test.tag, got)
+		}
+		if test.want != "" && (err != nil || got != test.want) {
+			t.Errorf("selectManifest(%q, %q) = %q, %v, want %q", test.ref, test.tag, got, err, test.want)
+		}
+	}
+}
+
+func TestRefNameTag(t *testing.T) {
+
+	tests := map[string]string{
+		"alpine:3.18":                     "3.18",
+		"registry.example.com:5000/app:1": "1",
+		"registry.example.com:5000/app":   "registry.example.com:5000/app",
+		"1.0":                             "1.0",
+	}
+
+	for refName, want := range tests {
+		if got := refNameTag(refName); got != want {
+			t.Errorf("refNameTag(%q) = %q, want %q", refName, got, want)
+		}
+	}
+}
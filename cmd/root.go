@@ -18,11 +18,16 @@ package cmd
 import (
 	"flag"
 	"os"
+	"strconv"
 
 	"github.com/spf13/cobra"
+	"github.com/zawachte-msft/bupkis/pkg/registry"
 )
 
 type Options struct {
+	pullSecrets              []string
+	credentialProviderConfig string
+	probeRegistries          bool
 }
 
 var opts = &Options{}
@@ -44,4 +49,16 @@ func Execute() {
 
 func init() {
 	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	RootCmd.PersistentFlags().StringArrayVarP(&opts.pullSecrets, "pull-secret", "", nil, "Kubernetes image pull secret file, or directory of them, to read credentials from")
+	RootCmd.PersistentFlags().StringVarP(&opts.credentialProviderConfig, "credential-provider-config", "", os.Getenv("BUPKIS_CREDENTIAL_PROVIDER_CONFIG"), "file listing credential provider binaries that supply short lived registry credentials")
+	probeRegistries, _ := strconv.ParseBool(os.Getenv("BUPKIS_PROBE_REGISTRIES"))
+	RootCmd.PersistentFlags().BoolVarP(&opts.probeRegistries, "probe-registries", "", probeRegistries, "ask registries without a well-known hostname whether they run Harbor, to list their repositories through its API")
+}
+
+// newRegistryClient creates a registry client that also uses the credentials of the global flags
+func newRegistryClient(options registry.RegistryClientOptions) (registry.Client, error) {
+	options.PullSecrets = opts.pullSecrets
+	options.CredentialProviderConfig = opts.credentialProviderConfig
+	options.ProbeRegistries = opts.probeRegistries
+	return registry.New(options)
 }
@@ -0,0 +1,164 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/zawachte-msft/bupkis/pkg/filter"
+	"github.com/zawachte-msft/bupkis/pkg/formatter"
+	"github.com/zawachte-msft/bupkis/pkg/registry"
+	"github.com/zawachte-msft/bupkis/pkg/util"
+)
+
+type searchOptions struct {
+	pattern    string
+	mode       string
+	ignoreCase bool
+	details    bool
+}
+
+var searchOpts = &searchOptions{}
+
+var searchCmd = &cobra.Command{
+	Use:   "search <pattern>",
+	Short: "search repositories and tags across container registries",
+	Long:  "search the repository names and tags of every registry you are logged in to. Only the catalog and tag lists are read unless --details is given.",
+	Example: "	bupkis search payments-api",
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		searchOpts.pattern = args[0]
+		return runSearch()
+	},
+}
+
+func init() {
+	searchCmd.Flags().StringVarP(&searchOpts.mode, "mode", "m", "substring", "how to match the pattern: substring, glob or regex")
+	searchCmd.Flags().BoolVarP(&searchOpts.ignoreCase, "ignore-case", "i", false, "match regardless of case")
+	searchCmd.Flags().BoolVarP(&searchOpts.details, "details", "", false, "fetch the manifest of every matching tag to show when it was created")
+	RootCmd.AddCommand(searchCmd)
+}
+
+func runSearch() error {
+
+	match, err := searchMatcher()
+	if err != nil {
+		return err
+	}
+
+	client, err := newRegistryClient(registry.RegistryClientOptions{})
+	if err != nil {
+		return err
+	}
+
+	hostnames := client.GetHostnames()
+	if len(hostnames) == 0 {
+		return fmt.Errorf("no registry credentials found, log in to a registry with bupkis login first")
+	}
+
+	data := [][]string{}
+	images := []registry.ImageData{}
+
+	for _, hostname := range hostnames {
+		repos, err := client.GetCatalog(hostname)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "WARNING: skipping %s: %v\n", hostname, err)
+			continue
+		}
+
+		for _, repo := range repos {
+			tags, err := client.GetTags(hostname, repo)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "WARNING: skipping %s/%s: %v\n", hostname, repo, err)
+				continue
+			}
+
+			matched := "repository"
+			if !match(repo) {
+				matched = "tag"
+				tags = filter.Tags(tags, match)
+			}
+			if len(tags) == 0 && matched == "tag" {
+				continue
+			}
+
+			if !searchOpts.details {
+				data = append(data, []string{fmt.Sprintf("%s/%s", hostname, repo), matched, summarizeList(tags, 5)})
+				continue
+			}
+
+			for _, tag := range tags {
+				image, err := client.GetImageData(hostname, repo, tag)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "WARNING: skipping %s/%s:%s: %v\n", hostname, repo, tag, err)
+					continue
+				}
+				images = append(images, image)
+			}
+		}
+	}
+
+	if searchOpts.details {
+		formatter.PrintOutput(util.ImagesToNestedArray(images))
+	} else {
+		formatter.PrintTable([]string{"Name", "Matched", "Tags"}, data)
+	}
+
+	return nil
+}
+
+func searchMatcher() (filter.Matcher, error) {
+
+	// regular expressions ignore case with a flag, as lowercasing them would
+	// turn classes like \D and \W into their opposites
+	if searchOpts.mode == "regex" {
+		pattern := searchOpts.pattern
+		if searchOpts.ignoreCase {
+			pattern = "(?i)" + pattern
+		}
+		return filter.Regex(pattern)
+	}
+
+	pattern := searchOpts.pattern
+	if searchOpts.ignoreCase {
+		pattern = strings.ToLower(pattern)
+	}
+
+	var match filter.Matcher
+	var err error
+	switch searchOpts.mode {
+	case "substring":
+		match = filter.Substring(pattern)
+	case "glob":
+		match, err = filter.Glob(pattern)
+	default:
+		return nil, fmt.Errorf("unknown search mode %q", searchOpts.mode)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !searchOpts.ignoreCase {
+		return match, nil
+	}
+
+	return func(name string) bool {
+		return match(strings.ToLower(name))
+	}, nil
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import "testing"
+
+func TestSearchMatcher(t *testing.T) {
+
+	saved := *searchOpts
+	defer func() { *searchOpts = saved }()
+
+	tests := []struct {
+		mode       string
+		pattern    string
+		ignoreCase bool
+		name       string
+		want       bool
+	}{
+		{"substring", "Web", false, "team/webapp", false},
+		{"substring", "Web", true, "team/webapp", true},
+		{"glob", "team/*", false, "team/a/b", true},
+		{"glob", "TEAM/?", true, "team/a", true},
+		{"glob", "team/?", false, "team/ab", false},
+		{"regex", `^team/\D+$`, false, "team/web", true},
+		// ignoring case must not turn \D into \d
+		{"regex", `^team/\D+$`, true, "TEAM/web", true},
+		{"regex", `^team/\D+$`, true, "team/web1", false},
+	}
+
+	for _, test := range tests {
+		searchOpts.mode = test.mode
+		searchOpts.pattern = test.pattern
+		searchOpts.ignoreCase = test.ignoreCase
+
+		match, err := searchMatcher()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := match(test.name); got != test.want {
+			t.Errorf("%s %q (ignore case %v) matches %q = %v, want %v", test.mode, test.pattern, test.ignoreCase, test.name, got, test.want)
+		}
+	}
+
+	searchOpts.mode = "fuzzy"
+	if _, err := searchMatcher(); err == nil {
+		t.Error("searchMatcher accepted an unknown mode")
+	}
+}
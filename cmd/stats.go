@@ -0,0 +1,143 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/docker/go-units"
+	"github.com/spf13/cobra"
+	"github.com/zawachte-msft/bupkis/pkg/analysis"
+	"github.com/zawachte-msft/bupkis/pkg/formatter"
+	"github.com/zawachte-msft/bupkis/pkg/registry"
+	"github.com/zawachte-msft/bupkis/pkg/util"
+)
+
+type statsOptions struct {
+	target string
+	sortBy string
+}
+
+var statsOpts = &statsOptions{}
+
+var statsCmd = &cobra.Command{
+	Use:   "stats [host[/repo]]",
+	Short: "show storage statistics of container registries",
+	Long:  "show storage statistics per repository: tag and manifest counts, logical and deduplicated size, oldest and newest tag and how old the tags are",
+	Example: "	bupkis stats bupkisimages.azurecr.io",
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 0 {
+			statsOpts.target = args[0]
+		}
+
+		return runStats()
+	},
+}
+
+func init() {
+	statsCmd.Flags().StringVarP(&statsOpts.sortBy, "sort", "", "unique-size", "sort repositories by unique-size, size, tags or name")
+	RootCmd.AddCommand(statsCmd)
+}
+
+func runStats() error {
+
+	imageData := util.ParseImageName(statsOpts.target)
+
+	client, err := newRegistryClient(registry.RegistryClientOptions{Hostname: imageData.Hostname, Repository: imageData.Name, AllPlatforms: true})
+	if err != nil {
+		return err
+	}
+
+	var images []registry.ImageData
+	switch {
+	case imageData.Name != "":
+		images, err = client.GetImageDataList(imageData.Hostname, imageData.Name)
+	case imageData.Hostname != "":
+		images, err = client.GetReposByHostName(imageData.Hostname)
+	default:
+		images, err = client.GetRepos()
+	}
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	repoStats := analysis.RepoStatistics(images, now)
+
+	switch statsOpts.sortBy {
+	case "unique-size":
+		sort.SliceStable(repoStats, func(i, j int) bool { return repoStats[i].UniqueSize > repoStats[j].UniqueSize })
+	case "size":
+		sort.SliceStable(repoStats, func(i, j int) bool { return repoStats[i].Size > repoStats[j].Size })
+	case "tags":
+		sort.SliceStable(repoStats, func(i, j int) bool { return repoStats[i].Tags > repoStats[j].Tags })
+	case "name":
+		sort.SliceStable(repoStats, func(i, j int) bool {
+			return repoStats[i].Hostname+"/"+repoStats[i].Name < repoStats[j].Hostname+"/"+repoStats[j].Name
+		})
+	default:
+		return fmt.Errorf("unknown sort order %q", statsOpts.sortBy)
+	}
+
+	data := [][]string{}
+	for _, stats := range repoStats {
+		data = append(data, statsRow(fmt.Sprintf("%s/%s", stats.Hostname, stats.Name), stats, now))
+	}
+	if len(repoStats) > 1 {
+		data = append(data, statsRow("TOTAL", analysis.TotalStatistics(images, now), now))
+	}
+
+	header := []string{"Repository", "Tags", "Manifests", "Size", "Unique Size", "Oldest", "Newest"}
+	for _, bound := range analysis.AgeBuckets {
+		header = append(header, fmt.Sprintf("< %dd", int(bound.Hours()/24)))
+	}
+	header = append(header, "Older", "Unknown")
+
+	formatter.PrintTable(header, data)
+
+	return nil
+}
+
+func statsRow(name string, stats analysis.RepoStats, now time.Time) []string {
+
+	row := []string{
+		name,
+		strconv.Itoa(stats.Tags),
+		strconv.Itoa(stats.Manifests),
+		units.HumanSize(float64(stats.Size)),
+		units.HumanSize(float64(stats.UniqueSize)),
+		describeTagAge(stats.Oldest, now),
+		describeTagAge(stats.Newest, now),
+	}
+
+	for _, count := range stats.Ages {
+		row = append(row, strconv.Itoa(count))
+	}
+
+	return row
+}
+
+func describeTagAge(image registry.ImageData, now time.Time) string {
+	if image.Created.IsZero() {
+		return ""
+	}
+
+	return fmt.Sprintf("%s (%s ago)", image.Tag, units.HumanDuration(now.Sub(image.Created)))
+}
@@ -0,0 +1,311 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+
+	digest "github.com/opencontainers/go-digest"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/spf13/cobra"
+	"github.com/zawachte-msft/bupkis/pkg/filter"
+	"github.com/zawachte-msft/bupkis/pkg/formatter"
+	"github.com/zawachte-msft/bupkis/pkg/registry"
+	"github.com/zawachte-msft/bupkis/pkg/util"
+	"sigs.k8s.io/yaml"
+)
+
+type syncOptions struct {
+	config          string
+	dryRun          bool
+	deleteExtraTags bool
+}
+
+var syncOpts = &syncOptions{}
+
+// syncConfig is the file given to bupkis sync
+type syncConfig struct {
+	// Destination is the host and path prefix mirrored repositories are copied under
+	Destination string `json:"destination"`
+	// DeleteExtraTags removes destination tags that are not selected at the source
+	DeleteExtraTags bool                   `json:"deleteExtraTags"`
+	Repositories    []syncRepositoryConfig `json:"repositories"`
+}
+
+type syncRepositoryConfig struct {
+	// Source is the host and repository to mirror
+	Source string `json:"source"`
+	// Destination overrides the host and repository to mirror into
+	Destination string `json:"destination,omitempty"`
+	// TagRegex selects the tags to mirror by regular expression
+	TagRegex string `json:"tagRegex,omitempty"`
+	// Semver selects the tags to mirror by semantic version constraint
+	Semver string `json:"semver,omitempty"`
+}
+
+type syncResult struct {
+	source      string
+	destination string
+	copied      int
+	unchanged   int
+	deleted     int
+	failed      int
+}
+
+var syncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "mirror repositories from one registry to another",
+	Long:  "mirror repositories from one registry to another. Only tags whose digest differs between source and destination are copied.",
+	Example: "	bupkis sync --config mirror.yaml",
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runSync()
+	},
+}
+
+func init() {
+	syncCmd.Flags().StringVarP(&syncOpts.config, "config", "c", "", "sync configuration file")
+	syncCmd.Flags().BoolVarP(&syncOpts.dryRun, "dry-run", "", false, "report what would change without copying or deleting")
+	syncCmd.Flags().BoolVarP(&syncOpts.deleteExtraTags, "delete", "", false, "delete destination tags that are not selected at the source")
+	syncCmd.MarkFlagRequired("config")
+	RootCmd.AddCommand(syncCmd)
+}
+
+func runSync() error {
+
+	content, err := ioutil.ReadFile(syncOpts.config)
+	if err != nil {
+		return err
+	}
+
+	config := syncConfig{}
+	if err := yaml.UnmarshalStrict(content, &config); err != nil {
+		return fmt.Errorf("invalid sync configuration %s: %v", syncOpts.config, err)
+	}
+	for _, repoConfig := range config.Repositories {
+		if repoConfig.Destination == "" && config.Destination == "" {
+			return fmt.Errorf("invalid sync configuration %s: %s has no destination", syncOpts.config, repoConfig.Source)
+		}
+	}
+
+	clients := map[string]registry.Client{}
+	clientFor := func(hostname string) (registry.Client, error) {
+		if client, ok := clients[hostname]; ok {
+			return client, nil
+		}
+		client, err := newRegistryClient(registry.RegistryClientOptions{Hostname: hostname})
+		if err != nil {
+			return nil, err
+		}
+		clients[hostname] = client
+		return client, nil
+	}
+
+	results := []syncResult{}
+	failed := false
+
+	for _, repoConfig := range config.Repositories {
+		result, err := syncRepository(config, repoConfig, clientFor)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %s: %v\n", repoConfig.Source, err)
+			result.failed++
+		}
+		if result.failed != 0 {
+			failed = true
+		}
+		results = append(results, result)
+	}
+
+	data := [][]string{}
+	for _, result := range results {
+		data = append(data, []string{
+			result.source,
+			result.destination,
+			strconv.Itoa(result.copied),
+			strconv.Itoa(result.unchanged),
+			strconv.Itoa(result.deleted),
+			strconv.Itoa(result.failed),
+		})
+	}
+	formatter.PrintTable([]string{"Source", "Destination", "Copied", "Unchanged", "Deleted", "Failed"}, data)
+
+	if failed {
+		return fmt.Errorf("sync finished with failures")
+	}
+	return nil
+}
+
+func syncRepository(config syncConfig, repoConfig syncRepositoryConfig, clientFor func(string) (registry.Client, error)) (syncResult, error) {
+
+	source := util.ParseImageName(repoConfig.Source)
+	destinationName := repoConfig.Destination
+	if destinationName == "" {
+		destinationName = fmt.Sprintf("%s/%s", config.Destination, source.Name)
+	}
+	destination := util.ParseImageName(destinationName)
+
+	result := syncResult{
+		source:      fmt.Sprintf("%s/%s", source.Hostname, source.Name),
+		destination: fmt.Sprintf("%s/%s", destination.Hostname, destination.Name),
+	}
+
+	match := filter.All()
+	if repoConfig.TagRegex != "" {
+		tagRegex, err := filter.Regex(repoConfig.TagRegex)
+		if err != nil {
+			return result, err
+		}
+		match = filter.All(match, tagRegex)
+	}
+	if repoConfig.Semver != "" {
+		constraint, err := filter.SemverConstraint(repoConfig.Semver)
+		if err != nil {
+			return result, err
+		}
+		match = filter.All(match, constraint)
+	}
+
+	sourceClient, err := clientFor(source.Hostname)
+	if err != nil {
+		return result, err
+	}
+	destinationClient, err := clientFor(destination.Hostname)
+	if err != nil {
+		return result, err
+	}
+
+	sourceTags, err := sourceClient.GetTags(source.Hostname, source.Name)
+	if err != nil {
+		return result, err
+	}
+	sourceTags = filter.Tags(sourceTags, match)
+
+	destinationTags, err := destinationClient.GetTags(destination.Hostname, destination.Name)
+	if err != nil && !registry.IsNotFound(err) {
+		return result, err
+	}
+
+	existing := map[string]bool{}
+	for _, tag := range destinationTags {
+		existing[tag] = true
+	}
+
+	// kept holds the digests the selected tags point at in the destination,
+	// which must survive deleting the extra tags. It is incomplete when a
+	// destination digest could not be read.
+	selected := map[string]bool{}
+	kept := map[digest.Digest]bool{}
+	keptComplete := true
+	for _, tag := range sourceTags {
+		selected[tag] = true
+
+		var destinationDigest digest.Digest
+		if existing[tag] {
+			destinationDigest, err = destinationClient.GetManifestDigest(destination.Hostname, destination.Name, tag)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "ERROR: %s:%s: %v\n", result.destination, tag, err)
+				result.failed++
+				keptComplete = false
+				continue
+			}
+			kept[destinationDigest] = true
+		}
+
+		sourceDigest, err := sourceClient.GetManifestDigest(source.Hostname, source.Name, tag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %s:%s: %v\n", result.source, tag, err)
+			result.failed++
+			continue
+		}
+		kept[sourceDigest] = true
+
+		if destinationDigest == sourceDigest {
+			result.unchanged++
+			continue
+		}
+
+		fmt.Printf("copy %s:%s -> %s:%s\n", result.source, tag, result.destination, tag)
+		if syncOpts.dryRun {
+			result.copied++
+			continue
+		}
+
+		opts := copyOptions{progress: ioutil.Discard}
+		if source.Hostname == destination.Hostname {
+			opts.mountFrom = []string{source.Name}
+		}
+
+		desc := v1.Descriptor{Digest: sourceDigest}
+		_, err = copyManifest(destinationClient, destination, desc, tag, registrySource{
+			client:   sourceClient,
+			hostname: source.Hostname,
+			repo:     source.Name,
+		}, opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %s:%s: %v\n", result.source, tag, err)
+			result.failed++
+			continue
+		}
+		result.copied++
+	}
+
+	if !config.DeleteExtraTags && !syncOpts.deleteExtraTags {
+		return result, nil
+	}
+	if !keptComplete {
+		fmt.Fprintf(os.Stderr, "WARNING: not deleting extra tags of %s, the manifests of some selected tags are unknown\n", result.destination)
+		return result, nil
+	}
+
+	// manifests are deleted by digest, which removes every tag on them, so a
+	// tag sharing its manifest with a selected tag cannot be deleted on its own
+	deleted := map[digest.Digest]bool{}
+	for _, tag := range destinationTags {
+		if selected[tag] {
+			continue
+		}
+
+		dgst, err := destinationClient.GetManifestDigest(destination.Hostname, destination.Name, tag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %s:%s: %v\n", result.destination, tag, err)
+			result.failed++
+			continue
+		}
+		if kept[dgst] {
+			fmt.Fprintf(os.Stderr, "WARNING: not deleting %s:%s, its manifest %s is also tagged with a selected tag\n", result.destination, tag, dgst)
+			continue
+		}
+
+		fmt.Printf("delete %s:%s\n", result.destination, tag)
+		if syncOpts.dryRun || deleted[dgst] {
+			result.deleted++
+			continue
+		}
+
+		if err := destinationClient.DeleteManifest(destination.Hostname, destination.Name, dgst); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %s:%s: %v\n", result.destination, tag, err)
+			result.failed++
+			continue
+		}
+		deleted[dgst] = true
+		result.deleted++
+	}
+
+	return result, nil
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"fmt"
+	"testing"
+
+	digest "github.com/opencontainers/go-digest"
+	"github.com/zawachte-msft/bupkis/pkg/registry"
+)
+
+// fakeSyncClient serves tags and manifest digests from memory. The methods
+// sync does not need are left to the nil embedded Client.
+type fakeSyncClient struct {
+	registry.Client
+	tags       []string
+	digests    map[string]digest.Digest
+	digestErrs map[string]error
+	deleted    []digest.Digest
+}
+
+func (c *fakeSyncClient) GetTags(hostname string, repo string) ([]string, error) {
+	return c.tags, nil
+}
+
+func (c *fakeSyncClient) GetManifestDigest(hostname string, repo string, reference string) (digest.Digest, error) {
+	if err := c.digestErrs[reference]; err != nil {
+		return "", err
+	}
+	return c.digests[reference], nil
+}
+
+func (c *fakeSyncClient) DeleteManifest(hostname string, repo string, dgst digest.Digest) error {
+	c.deleted = append(c.deleted, dgst)
+	return nil
+}
+
+func runSyncRepository(t *testing.T, source *fakeSyncClient, destination *fakeSyncClient, repoConfig syncRepositoryConfig) syncResult {
+
+	dryRun := syncOpts.dryRun
+	syncOpts.dryRun = true
+	defer func() { syncOpts.dryRun = dryRun }()
+
+	config := syncConfig{Destination: "mirror.example.com", DeleteExtraTags: true}
+	repoConfig.Source = "registry.example.com/app"
+
+	result, err := syncRepository(config, repoConfig, func(hostname string) (registry.Client, error) {
+		if hostname == "mirror.example.com" {
+			return destination, nil
+		}
+		return source, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestSyncRepository(t *testing.T) {
+
+	one, two, old := digest.FromString("1"), digest.FromString("2"), digest.FromString("old")
+	source := &fakeSyncClient{
+		tags:    []string{"1.0.0", "2.0.0", "nightly"},
+		digests: map[string]digest.Digest{"1.0.0": one, "2.0.0": two, "nightly": two},
+	}
+	destination := &fakeSyncClient{
+		tags:    []string{"1.0.0", "0.9.0", "stable"},
+		digests: map[string]digest.Digest{"1.0.0": one, "0.9.0": old, "stable": one},
+	}
+
+	result := runSyncRepository(t, source, destination, syncRepositoryConfig{Semver: ">= 1"})
+
+	if result.destination != "mirror.example.com/app" {
+		t.Errorf("destination = %q, want mirror.example.com/app", result.destination)
+	}
+	// stable shares its manifest with 1.0.0, so only 0.9.0 is deleted
+	if result.copied != 1 || result.unchanged != 1 || result.deleted != 1 || result.failed != 0 {
+		t.Errorf("result = %+v, want 2.0.0 copied, 1.0.0 unchanged and 0.9.0 deleted", result)
+	}
+}
+
+func TestSyncRepositoryDestinationDigestError(t *testing.T) {
+
+	one, two, old := digest.FromString("1"), digest.FromString("2"), digest.FromString("old")
+	source := &fakeSyncClient{
+		tags:    []string{"1.0.0", "2.0.0"},
+		digests: map[string]digest.Digest{"1.0.0": one, "2.0.0": two},
+	}
+	destination := &fakeSyncClient{
+		tags:       []string{"1.0.0", "2.0.0", "0.9.0"},
+		digests:    map[string]digest.Digest{"1.0.0": one, "0.9.0": old},
+		digestErrs: map[string]error{"2.0.0": fmt.Errorf("unavailable")},
+	}
+
+	result := runSyncRepository(t, source, destination, syncRepositoryConfig{})
+
+	if result.failed != 1 || result.unchanged != 1 || result.copied != 0 {
+		t.Errorf("result = %+v, want 2.0.0 failed and 1.0.0 unchanged", result)
+	}
+	// the manifest of 2.0.0 is unknown and might be the one 0.9.0 points at
+	if result.deleted != 0 {
+		t.Errorf("deleted %d extra tags without knowing every selected manifest", result.deleted)
+	}
+}
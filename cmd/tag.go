@@ -0,0 +1,96 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/docker/distribution/reference"
+	"github.com/spf13/cobra"
+	"github.com/zawachte-msft/bupkis/pkg/registry"
+	"github.com/zawachte-msft/bupkis/pkg/util"
+)
+
+var anchoredTagRegexp = regexp.MustCompile(`^` + reference.TagRegexp.String() + `$`)
+
+type tagOptions struct {
+	image   string
+	newTags []string
+}
+
+var tagOpts = &tagOptions{}
+
+var tagCmd = &cobra.Command{
+	Use:   "tag <existing-ref> <new-tag>...",
+	Short: "tag an image in a container registry without pulling it",
+	Long:  "tag an image in a container registry without pulling it. The manifest is copied byte for byte so the digest is preserved.",
+	Example: "	bupkis tag bupkisimages.azurecr.io/docs-image:1.2.3 stable 1.2",
+	Args: cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		tagOpts.image = args[0]
+		tagOpts.newTags = args[1:]
+		return runTag()
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(tagCmd)
+}
+
+func runTag() error {
+
+	imageData := util.ParseImageReference(tagOpts.image)
+
+	for _, newTag := range tagOpts.newTags {
+		if !anchoredTagRegexp.MatchString(newTag) {
+			return fmt.Errorf("invalid tag %q", newTag)
+		}
+	}
+
+	reference := imageData.Tag
+	if imageData.Digest != "" {
+		reference = imageData.Digest.String()
+	}
+	if reference == "" {
+		reference = "latest"
+	}
+
+	client, err := newRegistryClient(registry.RegistryClientOptions{Hostname: imageData.Hostname, Repository: imageData.Name})
+	if err != nil {
+		return err
+	}
+
+	manifest, err := client.GetManifest(imageData.Hostname, imageData.Name, reference)
+	if err != nil {
+		return err
+	}
+
+	if manifest.IsSchema1() {
+		return fmt.Errorf("%s is a schema1 manifest, which embeds its tag and cannot be retagged", tagOpts.image)
+	}
+
+	for _, newTag := range tagOpts.newTags {
+		dgst, err := client.PutManifest(imageData.Hostname, imageData.Name, newTag, manifest)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Tagged %s/%s:%s@%s\n", imageData.Hostname, imageData.Name, newTag, dgst)
+	}
+
+	return nil
+}
@@ -3,12 +3,18 @@ module github.com/zawachte-msft/bupkis
 go 1.14
 
 require (
+	github.com/BurntSushi/toml v0.4.1
+	github.com/Masterminds/semver/v3 v3.1.1
 	github.com/containerd/containerd v1.4.3
 	github.com/docker/cli v0.0.0-20200130152716-5d0cf8839492
 	github.com/docker/distribution v2.7.1+incompatible
 	github.com/docker/docker v1.4.2-0.20200203170920-46ec8731fbce
 	github.com/docker/go-units v0.4.0
 	github.com/olekukonko/tablewriter v0.0.5-0.20201029120751-42e21c7531a3
+	github.com/opencontainers/go-digest v1.0.0
+	github.com/opencontainers/image-spec v1.0.1
 	github.com/spf13/cobra v1.1.1
+	github.com/spf13/pflag v1.0.5
 	github.com/zwachtel11/peg v0.0.1
+	sigs.k8s.io/yaml v1.2.0
 )
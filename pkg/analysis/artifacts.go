@@ -0,0 +1,47 @@
+package analysis
+
+import (
+	"github.com/zawachte-msft/bupkis/pkg/registry"
+)
+
+// SplitArtifacts separates signatures, attestations, SBOMs and other
+// artifacts from the images they describe.
+func SplitArtifacts(images []registry.ImageData) ([]registry.ImageData, []registry.ImageData) {
+
+	returnImages := []registry.ImageData{}
+	artifacts := []registry.ImageData{}
+
+	for _, image := range images {
+		if image.IsArtifact() {
+			artifacts = append(artifacts, image)
+			continue
+		}
+		returnImages = append(returnImages, image)
+	}
+
+	return returnImages, artifacts
+}
+
+// ArtifactKinds returns the distinct kinds of artifacts attached to each
+// subject image, keyed by ArtifactKey of the subject.
+func ArtifactKinds(artifacts []registry.ImageData) map[string][]string {
+
+	kinds := map[string][]string{}
+	for _, artifact := range artifacts {
+		if artifact.Subject == "" {
+			continue
+		}
+
+		key := artifact.Hostname + "/" + artifact.Name + "@" + artifact.Subject.String()
+		if !contains(kinds[key], artifact.ArtifactKind) {
+			kinds[key] = append(kinds[key], artifact.ArtifactKind)
+		}
+	}
+
+	return kinds
+}
+
+// ArtifactKey identifies an image the way ArtifactKinds keys its subjects
+func ArtifactKey(image registry.ImageData) string {
+	return image.Hostname + "/" + image.Name + "@" + image.Digest.String()
+}
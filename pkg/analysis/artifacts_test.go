@@ -0,0 +1,35 @@
+package analysis
+
+import (
+	"reflect"
+	"testing"
+
+	digest "github.com/opencontainers/go-digest"
+	"github.com/zawachte-msft/bupkis/pkg/registry"
+)
+
+func TestSplitArtifacts(t *testing.T) {
+
+	app := registry.ImageData{Hostname: "registry.example.com", Name: "app", Tag: "1.0", Digest: digest.FromString("app")}
+	signature := registry.ImageData{Hostname: "registry.example.com", Name: "app", Tag: "sig", ArtifactKind: registry.ArtifactSignature, Subject: app.Digest}
+	otherSignature := signature
+	otherSignature.Tag = "sig2"
+	sbom := registry.ImageData{Hostname: "registry.example.com", Name: "app", Tag: "sbom", ArtifactKind: registry.ArtifactSBOM, Subject: app.Digest}
+	// the same subject in another repository is another image
+	mirrored := registry.ImageData{Hostname: "registry.example.com", Name: "mirror/app", Tag: "sig", ArtifactKind: registry.ArtifactSignature, Subject: app.Digest}
+	orphan := registry.ImageData{Hostname: "registry.example.com", Name: "app", Tag: "bundle", ArtifactKind: registry.ArtifactOther}
+
+	images, artifacts := SplitArtifacts([]registry.ImageData{signature, app, otherSignature, sbom, mirrored, orphan})
+	if len(images) != 1 || images[0].Tag != "1.0" || len(artifacts) != 5 {
+		t.Fatalf("SplitArtifacts() = %d images, %d artifacts, want app:1.0 and 5 artifacts", len(images), len(artifacts))
+	}
+
+	kinds := ArtifactKinds(artifacts)
+	want := map[string][]string{
+		ArtifactKey(app): {registry.ArtifactSignature, registry.ArtifactSBOM},
+		"registry.example.com/mirror/app@" + app.Digest.String(): {registry.ArtifactSignature},
+	}
+	if !reflect.DeepEqual(kinds, want) {
+		t.Errorf("ArtifactKinds() = %v, want %v", kinds, want)
+	}
+}
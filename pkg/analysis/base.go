@@ -0,0 +1,105 @@
+package analysis
+
+import (
+	"time"
+
+	digest "github.com/opencontainers/go-digest"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/zawachte-msft/bupkis/pkg/registry"
+)
+
+// BaseVersion is one version of a base image, possibly tagged several times
+type BaseVersion struct {
+	Digest  digest.Digest
+	Tags    []string
+	Created time.Time
+	Layers  []v1.Descriptor
+}
+
+// BaseUsage is an image built on a version of a base image
+type BaseUsage struct {
+	Image registry.ImageData
+	Base  BaseVersion
+}
+
+// BaseVersions groups the images of a base repository by digest. Images
+// without layers cannot be recognized in other images and are left out.
+func BaseVersions(images []registry.ImageData) []BaseVersion {
+
+	order := []digest.Digest{}
+	versions := map[digest.Digest]*BaseVersion{}
+
+	for _, image := range images {
+		if len(image.Layers) == 0 {
+			continue
+		}
+		if _, ok := versions[image.Digest]; !ok {
+			order = append(order, image.Digest)
+			versions[image.Digest] = &BaseVersion{
+				Digest:  image.Digest,
+				Created: image.Created,
+				Layers:  image.Layers,
+			}
+		}
+		if image.Tag != "" && image.Tag != image.Digest.String() && !contains(versions[image.Digest].Tags, image.Tag) {
+			versions[image.Digest].Tags = append(versions[image.Digest].Tags, image.Tag)
+		}
+	}
+
+	returnVersions := []BaseVersion{}
+	for _, dgst := range order {
+		returnVersions = append(returnVersions, *versions[dgst])
+	}
+
+	return returnVersions
+}
+
+// FindBaseUsage matches every image to the base version whose layers are the
+// longest prefix of the image's own layers. Images built on none of the
+// versions are left out.
+func FindBaseUsage(images []registry.ImageData, versions []BaseVersion) []BaseUsage {
+
+	usages := []BaseUsage{}
+
+	for _, image := range images {
+		best := -1
+		for i, version := range versions {
+			if !HasLayerPrefix(image.Layers, version.Layers) {
+				continue
+			}
+			if best == -1 || len(version.Layers) > len(versions[best].Layers) {
+				best = i
+			}
+		}
+
+		if best != -1 {
+			usages = append(usages, BaseUsage{Image: image, Base: versions[best]})
+		}
+	}
+
+	return usages
+}
+
+// HasLayerPrefix reports whether layers starts with every layer of prefix
+func HasLayerPrefix(layers []v1.Descriptor, prefix []v1.Descriptor) bool {
+	if len(prefix) == 0 || len(prefix) > len(layers) {
+		return false
+	}
+
+	for i := range prefix {
+		if layers[i].Digest != prefix[i].Digest {
+			return false
+		}
+	}
+
+	return true
+}
+
+func contains(items []string, item string) bool {
+	for _, existing := range items {
+		if existing == item {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package analysis
+
+import (
+	"testing"
+	"time"
+
+	digest "github.com/opencontainers/go-digest"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/zawachte-msft/bupkis/pkg/registry"
+)
+
+func TestBaseVersions(t *testing.T) {
+
+	layer := blob("base", 100)
+	old, current := digest.FromString("old"), digest.FromString("current")
+	images := []registry.ImageData{
+		{Tag: "22.04", Digest: current, Layers: []v1.Descriptor{layer, blob("patch", 10)}},
+		{Tag: "22.04.1", Digest: current, Layers: []v1.Descriptor{layer, blob("patch", 10)}},
+		{Tag: old.String(), Digest: old, Created: testNow.Add(-time.Hour), Layers: []v1.Descriptor{layer}},
+		{Tag: "sbom", Digest: digest.FromString("sbom")},
+	}
+
+	versions := BaseVersions(images)
+	if len(versions) != 2 {
+		t.Fatalf("BaseVersions() = %+v, want the current and the old version", versions)
+	}
+	if versions[0].Digest != current || len(versions[0].Tags) != 2 {
+		t.Errorf("first version = %+v, want %s tagged 22.04 and 22.04.1", versions[0], current)
+	}
+	// an untagged version is not tagged with its own digest
+	if versions[1].Digest != old || len(versions[1].Tags) != 0 {
+		t.Errorf("second version = %+v, want %s without tags", versions[1], old)
+	}
+}
+
+func TestFindBaseUsage(t *testing.T) {
+
+	osLayer, runtimeLayer := blob("os", 100), blob("runtime", 50)
+	versions := []BaseVersion{
+		{Digest: digest.FromString("os"), Layers: []v1.Descriptor{osLayer}},
+		{Digest: digest.FromString("runtime"), Layers: []v1.Descriptor{osLayer, runtimeLayer}},
+	}
+	images := []registry.ImageData{
+		{Name: "app", Layers: []v1.Descriptor{osLayer, runtimeLayer, blob("app", 10)}},
+		{Name: "tool", Layers: []v1.Descriptor{osLayer, blob("tool", 10)}},
+		{Name: "other", Layers: []v1.Descriptor{blob("other", 10), osLayer}},
+	}
+
+	usages := FindBaseUsage(images, versions)
+	if len(usages) != 2 {
+		t.Fatalf("FindBaseUsage() = %+v, want app and tool", usages)
+	}
+	// the longest matching version wins
+	if usages[0].Image.Name != "app" || usages[0].Base.Digest != versions[1].Digest {
+		t.Errorf("app is built on %s, want %s", usages[0].Base.Digest, versions[1].Digest)
+	}
+	if usages[1].Image.Name != "tool" || usages[1].Base.Digest != versions[0].Digest {
+		t.Errorf("tool is built on %s, want %s", usages[1].Base.Digest, versions[0].Digest)
+	}
+}
+
+func TestHasLayerPrefix(t *testing.T) {
+
+	a, b := blob("a", 1), blob("b", 1)
+	tests := []struct {
+		layers []v1.Descriptor
+		prefix []v1.Descriptor
+		want   bool
+	}{
+		{[]v1.Descriptor{a, b}, []v1.Descriptor{a}, true},
+		{[]v1.Descriptor{a, b}, []v1.Descriptor{a, b}, true},
+		{[]v1.Descriptor{a, b}, []v1.Descriptor{b}, false},
+		{[]v1.Descriptor{a}, []v1.Descriptor{a, b}, false},
+		{[]v1.Descriptor{a}, nil, false},
+	}
+
+	for _, test := range tests {
+		if got := HasLayerPrefix(test.layers, test.prefix); got != test.want {
+			t.Errorf("HasLayerPrefix(%d layers, %d prefix layers) = %v, want %v", len(test.layers), len(test.prefix), got, test.want)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package analysis
+
+import (
+	"time"
+
+	digest "github.com/opencontainers/go-digest"
+	"github.com/zawachte-msft/bupkis/pkg/registry"
+)
+
+// DigestGroup is a manifest of a repository together with all tags pointing at it
+type DigestGroup struct {
+	Hostname string
+	Name     string
+	Digest   digest.Digest
+	Tags     []string
+	Created  time.Time
+	Size     int64
+	// Image is the first tag of the group
+	Image registry.ImageData
+}
+
+// GroupByDigest collapses the tags of a repository that point at the same
+// manifest into one group, in the order the manifests first appear in images.
+func GroupByDigest(images []registry.ImageData) []DigestGroup {
+
+	order := []string{}
+	groups := map[string]*DigestGroup{}
+
+	for _, image := range images {
+		key := image.Hostname + "/" + image.Name + "@" + image.Digest.String()
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+			groups[key] = &DigestGroup{
+				Hostname: image.Hostname,
+				Name:     image.Name,
+				Digest:   image.Digest,
+				Created:  image.Created,
+				Size:     image.Size,
+				Image:    image,
+			}
+		}
+		groups[key].Tags = append(groups[key].Tags, image.Tag)
+	}
+
+	returnGroups := []DigestGroup{}
+	for _, key := range order {
+		returnGroups = append(returnGroups, *groups[key])
+	}
+
+	return returnGroups
+}
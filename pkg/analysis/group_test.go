@@ -0,0 +1,39 @@
+package analysis
+
+import (
+	"reflect"
+	"testing"
+
+	digest "github.com/opencontainers/go-digest"
+	"github.com/zawachte-msft/bupkis/pkg/registry"
+)
+
+func TestGroupByDigest(t *testing.T) {
+
+	one, two := digest.FromString("1"), digest.FromString("2")
+	images := []registry.ImageData{
+		{Hostname: "registry.example.com", Name: "app", Tag: "1.0", Digest: one, Size: 10},
+		{Hostname: "registry.example.com", Name: "app", Tag: "2.0", Digest: two, Size: 20},
+		{Hostname: "registry.example.com", Name: "app", Tag: "latest", Digest: two, Size: 20},
+		// the same manifest in another repository is a group of its own
+		{Hostname: "registry.example.com", Name: "mirror/app", Tag: "1.0", Digest: one, Size: 10},
+	}
+
+	groups := GroupByDigest(images)
+
+	got := [][]string{}
+	for _, group := range groups {
+		got = append(got, append([]string{group.Name, group.Digest.String()}, group.Tags...))
+	}
+	want := [][]string{
+		{"app", one.String(), "1.0"},
+		{"app", two.String(), "2.0", "latest"},
+		{"mirror/app", one.String(), "1.0"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GroupByDigest() = %v, want %v", got, want)
+	}
+	if groups[1].Image.Tag != "2.0" || groups[1].Size != 20 {
+		t.Errorf("group of %s = %+v, want the size and image of 2.0", two, groups[1])
+	}
+}
@@ -0,0 +1,87 @@
+package analysis
+
+import (
+	"time"
+
+	digest "github.com/opencontainers/go-digest"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/zawachte-msft/bupkis/pkg/registry"
+)
+
+// LineageNode is an image in the lineage graph. Tags of a repository that
+// point at the same digest share a node.
+type LineageNode struct {
+	Hostname string
+	Name     string
+	Tags     []string
+	Digest   digest.Digest
+	Size     int64
+	Created  time.Time
+	Layers   []v1.Descriptor
+}
+
+// LineageEdge connects an image to the image it was built on
+type LineageEdge struct {
+	Parent       int
+	Child        int
+	SharedLayers int
+}
+
+// Lineage is the parent/child graph of images inferred from their layers
+type Lineage struct {
+	Nodes []LineageNode
+	Edges []LineageEdge
+}
+
+// BuildLineage infers which image each image was built on: the image whose
+// layers are the longest strict prefix of its own layers.
+func BuildLineage(images []registry.ImageData) Lineage {
+
+	lineage := Lineage{}
+	nodes := map[string]int{}
+
+	for _, image := range images {
+		if len(image.Layers) == 0 {
+			continue
+		}
+
+		key := image.Hostname + "/" + image.Name + "@" + image.Digest.String()
+		if i, ok := nodes[key]; ok {
+			lineage.Nodes[i].Tags = append(lineage.Nodes[i].Tags, image.Tag)
+			continue
+		}
+
+		nodes[key] = len(lineage.Nodes)
+		lineage.Nodes = append(lineage.Nodes, LineageNode{
+			Hostname: image.Hostname,
+			Name:     image.Name,
+			Tags:     []string{image.Tag},
+			Digest:   image.Digest,
+			Size:     image.Size,
+			Created:  image.Created,
+			Layers:   image.Layers,
+		})
+	}
+
+	for child, childNode := range lineage.Nodes {
+		parent := -1
+		for candidate, candidateNode := range lineage.Nodes {
+			if len(candidateNode.Layers) >= len(childNode.Layers) || !HasLayerPrefix(childNode.Layers, candidateNode.Layers) {
+				continue
+			}
+			if parent == -1 || len(candidateNode.Layers) > len(lineage.Nodes[parent].Layers) {
+				parent = candidate
+			}
+		}
+
+		if parent != -1 {
+			lineage.Edges = append(lineage.Edges, LineageEdge{
+				Parent:       parent,
+				Child:        child,
+				SharedLayers: len(lineage.Nodes[parent].Layers),
+			})
+		}
+	}
+
+	return lineage
+}
@@ -0,0 +1,46 @@
+package analysis
+
+import (
+	"reflect"
+	"testing"
+
+	digest "github.com/opencontainers/go-digest"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/zawachte-msft/bupkis/pkg/registry"
+)
+
+func TestBuildLineage(t *testing.T) {
+
+	osLayer, runtimeLayer := blob("os", 100), blob("runtime", 50)
+	images := []registry.ImageData{
+		{Hostname: "registry.example.com", Name: "base/os", Tag: "1", Digest: digest.FromString("os"), Layers: []v1.Descriptor{osLayer}},
+		{Hostname: "registry.example.com", Name: "base/os", Tag: "latest", Digest: digest.FromString("os"), Layers: []v1.Descriptor{osLayer}},
+		{Hostname: "registry.example.com", Name: "base/runtime", Tag: "1", Digest: digest.FromString("runtime"), Layers: []v1.Descriptor{osLayer, runtimeLayer}},
+		{Hostname: "registry.example.com", Name: "app", Tag: "1", Digest: digest.FromString("app"), Layers: []v1.Descriptor{osLayer, runtimeLayer, blob("app", 10)}},
+		{Hostname: "registry.example.com", Name: "mirror/runtime", Tag: "1", Digest: digest.FromString("runtime"), Layers: []v1.Descriptor{osLayer, runtimeLayer}},
+		{Hostname: "registry.example.com", Name: "sbom", Tag: "1", Digest: digest.FromString("sbom")},
+	}
+
+	lineage := BuildLineage(images)
+
+	names := []string{}
+	for _, node := range lineage.Nodes {
+		names = append(names, node.Name)
+	}
+	if want := []string{"base/os", "base/runtime", "app", "mirror/runtime"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("nodes = %v, want %v", names, want)
+	}
+	if want := []string{"1", "latest"}; !reflect.DeepEqual(lineage.Nodes[0].Tags, want) {
+		t.Errorf("base/os tags = %v, want %v", lineage.Nodes[0].Tags, want)
+	}
+
+	// an identical image in another repository is a sibling, not a parent
+	want := []LineageEdge{
+		{Parent: 0, Child: 1, SharedLayers: 1},
+		{Parent: 1, Child: 2, SharedLayers: 2},
+		{Parent: 0, Child: 3, SharedLayers: 1},
+	}
+	if !reflect.DeepEqual(lineage.Edges, want) {
+		t.Errorf("edges = %+v, want %+v", lineage.Edges, want)
+	}
+}
@@ -0,0 +1,131 @@
+package analysis
+
+import (
+	"sort"
+	"strings"
+
+	digest "github.com/opencontainers/go-digest"
+	"github.com/zawachte-msft/bupkis/pkg/registry"
+)
+
+// SharedLayer is a layer used by images in more than one repository
+type SharedLayer struct {
+	Digest       digest.Digest
+	Size         int64
+	Repositories []string
+}
+
+// Savings is the space the registries would need if every repository stored
+// its own copy. Registries do not share storage, so a layer is still stored
+// once in each registry using it.
+func (l SharedLayer) Savings() int64 {
+	savings := int64(0)
+	for _, registrySavings := range l.SavingsByRegistry() {
+		savings += registrySavings
+	}
+	return savings
+}
+
+// SavingsByRegistry splits Savings by the hostname of the registries using the layer
+func (l SharedLayer) SavingsByRegistry() map[string]int64 {
+	repositories := map[string]int{}
+	for _, repository := range l.Repositories {
+		repositories[strings.SplitN(repository, "/", 2)[0]]++
+	}
+
+	savings := map[string]int64{}
+	for hostname, count := range repositories {
+		savings[hostname] = l.Size * int64(count-1)
+	}
+	return savings
+}
+
+// DuplicateManifest is a manifest pushed to more than one repository
+type DuplicateManifest struct {
+	Digest digest.Digest
+	Size   int64
+	Images []registry.ImageData
+}
+
+// Repositories returns the distinct repositories the manifest was pushed to
+func (m DuplicateManifest) Repositories() []string {
+	return distinctRepositories(m.Images)
+}
+
+// SharedLayers returns the layers used by more than one repository, the ones
+// saving the most space first. Multi-platform images contribute the layers of
+// the platform recorded in ImageData.Layers.
+func SharedLayers(images []registry.ImageData) []SharedLayer {
+
+	order := []digest.Digest{}
+	layers := map[digest.Digest]*SharedLayer{}
+	seen := map[digest.Digest]map[string]bool{}
+
+	for _, image := range images {
+		repository := image.Hostname + "/" + image.Name
+		for _, layer := range image.Layers {
+			if _, ok := layers[layer.Digest]; !ok {
+				order = append(order, layer.Digest)
+				layers[layer.Digest] = &SharedLayer{Digest: layer.Digest, Size: layer.Size}
+				seen[layer.Digest] = map[string]bool{}
+			}
+			if !seen[layer.Digest][repository] {
+				seen[layer.Digest][repository] = true
+				layers[layer.Digest].Repositories = append(layers[layer.Digest].Repositories, repository)
+			}
+		}
+	}
+
+	shared := []SharedLayer{}
+	for _, dgst := range order {
+		if len(layers[dgst].Repositories) > 1 {
+			shared = append(shared, *layers[dgst])
+		}
+	}
+
+	sort.SliceStable(shared, func(i, j int) bool { return shared[i].Savings() > shared[j].Savings() })
+
+	return shared
+}
+
+// DuplicateManifests returns the manifests that are byte-identical across
+// repositories, the ones in the most repositories first.
+func DuplicateManifests(images []registry.ImageData) []DuplicateManifest {
+
+	order := []digest.Digest{}
+	manifests := map[digest.Digest]*DuplicateManifest{}
+
+	for _, image := range images {
+		if _, ok := manifests[image.Digest]; !ok {
+			order = append(order, image.Digest)
+			manifests[image.Digest] = &DuplicateManifest{Digest: image.Digest, Size: image.Size}
+		}
+		manifests[image.Digest].Images = append(manifests[image.Digest].Images, image)
+	}
+
+	duplicates := []DuplicateManifest{}
+	for _, dgst := range order {
+		if len(manifests[dgst].Repositories()) > 1 {
+			duplicates = append(duplicates, *manifests[dgst])
+		}
+	}
+
+	sort.SliceStable(duplicates, func(i, j int) bool {
+		return len(duplicates[i].Repositories()) > len(duplicates[j].Repositories())
+	})
+
+	return duplicates
+}
+
+func distinctRepositories(images []registry.ImageData) []string {
+	repositories := []string{}
+	seen := map[string]bool{}
+	for _, image := range images {
+		repository := image.Hostname + "/" + image.Name
+		if !seen[repository] {
+			seen[repository] = true
+			repositories = append(repositories, repository)
+		}
+	}
+	return repositories
+}
@@ -0,0 +1,57 @@
+package analysis
+
+import (
+	"reflect"
+	"testing"
+
+	digest "github.com/opencontainers/go-digest"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/zawachte-msft/bupkis/pkg/registry"
+)
+
+func TestSharedLayers(t *testing.T) {
+
+	base, app, web := blob("base", 100), blob("app", 50), blob("web", 30)
+	images := []registry.ImageData{
+		{Hostname: "a.example.com", Name: "app", Tag: "1", Layers: []v1.Descriptor{base, app}},
+		{Hostname: "a.example.com", Name: "app", Tag: "2", Layers: []v1.Descriptor{base, app}},
+		{Hostname: "a.example.com", Name: "web", Tag: "1", Layers: []v1.Descriptor{base, web}},
+		{Hostname: "b.example.com", Name: "web", Tag: "1", Layers: []v1.Descriptor{base, web}},
+	}
+
+	shared := SharedLayers(images)
+	if len(shared) != 2 || shared[0].Digest != base.Digest || shared[1].Digest != web.Digest {
+		t.Fatalf("SharedLayers() = %+v, want the base layer, then the web layer", shared)
+	}
+
+	// each registry stores its own copy, so only a.example.com saves space
+	want := map[string]int64{"a.example.com": 100, "b.example.com": 0}
+	if got := shared[0].SavingsByRegistry(); !reflect.DeepEqual(got, want) {
+		t.Errorf("base SavingsByRegistry() = %v, want %v", got, want)
+	}
+	if got := shared[0].Savings(); got != 100 {
+		t.Errorf("base Savings() = %d, want 100", got)
+	}
+	if got := shared[1].Savings(); got != 0 {
+		t.Errorf("web Savings() = %d, want 0 for a layer shared across registries only", got)
+	}
+}
+
+func TestDuplicateManifests(t *testing.T) {
+
+	shared, single := digest.FromString("shared"), digest.FromString("single")
+	images := []registry.ImageData{
+		{Hostname: "a.example.com", Name: "app", Tag: "1", Digest: single},
+		{Hostname: "a.example.com", Name: "app", Tag: "2", Digest: single},
+		{Hostname: "a.example.com", Name: "app", Tag: "3", Digest: shared},
+		{Hostname: "a.example.com", Name: "mirror/app", Tag: "3", Digest: shared},
+	}
+
+	duplicates := DuplicateManifests(images)
+	if len(duplicates) != 1 || duplicates[0].Digest != shared {
+		t.Fatalf("DuplicateManifests() = %+v, want only %s", duplicates, shared)
+	}
+	if want := []string{"a.example.com/app", "a.example.com/mirror/app"}; !reflect.DeepEqual(duplicates[0].Repositories(), want) {
+		t.Errorf("Repositories() = %v, want %v", duplicates[0].Repositories(), want)
+	}
+}
@@ -0,0 +1,121 @@
+package analysis
+
+import (
+	"time"
+
+	digest "github.com/opencontainers/go-digest"
+	"github.com/zawachte-msft/bupkis/pkg/registry"
+)
+
+// AgeBuckets are the upper bounds of the tag age distribution. Tags older
+// than the last bucket are counted in an extra bucket, and tags without a
+// creation time, like artifacts, in a final one.
+var AgeBuckets = []time.Duration{
+	7 * 24 * time.Hour,
+	30 * 24 * time.Hour,
+	90 * 24 * time.Hour,
+	365 * 24 * time.Hour,
+}
+
+// RepoStats summarizes the storage used by a repository, or by a whole registry
+type RepoStats struct {
+	Hostname string
+	Name     string
+	Tags     int
+	// Manifests counts the distinct digests the tags point at
+	Manifests int
+	// Size counts every tag in full, as if nothing was shared
+	Size int64
+	// UniqueSize counts each blob once, which is closer to what the registry stores
+	UniqueSize int64
+	Oldest     registry.ImageData
+	Newest     registry.ImageData
+	// Ages counts the tags by age, one entry per AgeBuckets entry plus one
+	// for older tags and one for tags of unknown age
+	Ages []int
+}
+
+// RepoStatistics summarizes the images of each repository, in the order the
+// repositories first appear in images.
+func RepoStatistics(images []registry.ImageData, now time.Time) []RepoStats {
+
+	order := []string{}
+	grouped := map[string][]registry.ImageData{}
+	for _, image := range images {
+		key := image.Hostname + "/" + image.Name
+		if _, ok := grouped[key]; !ok {
+			order = append(order, key)
+		}
+		grouped[key] = append(grouped[key], image)
+	}
+
+	returnStats := []RepoStats{}
+	for _, key := range order {
+		repoStats := summarize(grouped[key], now)
+		repoStats.Hostname = grouped[key][0].Hostname
+		repoStats.Name = grouped[key][0].Name
+		returnStats = append(returnStats, repoStats)
+	}
+
+	return returnStats
+}
+
+// TotalStatistics summarizes all images together, counting blobs shared
+// between repositories once.
+func TotalStatistics(images []registry.ImageData, now time.Time) RepoStats {
+	return summarize(images, now)
+}
+
+func summarize(images []registry.ImageData, now time.Time) RepoStats {
+
+	repoStats := RepoStats{
+		Ages: make([]int, len(AgeBuckets)+2),
+	}
+
+	manifests := map[digest.Digest]bool{}
+	blobs := map[digest.Digest]int64{}
+
+	for _, image := range images {
+		repoStats.Tags++
+		repoStats.Size += image.Size
+		manifests[image.Digest] = true
+
+		for _, blob := range image.Blobs {
+			blobs[blob.Digest] = blob.Size
+		}
+
+		if !image.Created.IsZero() {
+			if repoStats.Oldest.Created.IsZero() || image.Created.Before(repoStats.Oldest.Created) {
+				repoStats.Oldest = image
+			}
+			if image.Created.After(repoStats.Newest.Created) {
+				repoStats.Newest = image
+			}
+		}
+
+		repoStats.Ages[ageBucket(image.Created, now)]++
+	}
+
+	repoStats.Manifests = len(manifests)
+	for _, size := range blobs {
+		repoStats.UniqueSize += size
+	}
+
+	return repoStats
+}
+
+// ageBucket returns the index in RepoStats.Ages that counts an image created at the given time
+func ageBucket(created time.Time, now time.Time) int {
+
+	if created.IsZero() {
+		return len(AgeBuckets) + 1
+	}
+
+	for i, bound := range AgeBuckets {
+		if now.Sub(created) < bound {
+			return i
+		}
+	}
+
+	return len(AgeBuckets)
+}
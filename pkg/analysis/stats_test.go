@@ -0,0 +1,82 @@
+package analysis
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	digest "github.com/opencontainers/go-digest"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/zawachte-msft/bupkis/pkg/registry"
+)
+
+var testNow = time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+
+func blob(content string, size int64) v1.Descriptor {
+	return v1.Descriptor{Digest: digest.FromString(content), Size: size}
+}
+
+func TestAgeBucket(t *testing.T) {
+
+	day := 24 * time.Hour
+	tests := []struct {
+		created time.Time
+		want    int
+	}{
+		{testNow.Add(-time.Hour), 0},
+		{testNow.Add(-7 * day), 1},
+		{testNow.Add(-60 * day), 2},
+		{testNow.Add(-200 * day), 3},
+		{testNow.Add(-400 * day), 4},
+		{time.Time{}, 5},
+	}
+
+	for _, test := range tests {
+		if got := ageBucket(test.created, testNow); got != test.want {
+			t.Errorf("ageBucket(%v) = %d, want %d", test.created, got, test.want)
+		}
+	}
+}
+
+func TestRepoStatistics(t *testing.T) {
+
+	base := blob("base", 100)
+	images := []registry.ImageData{
+		{Hostname: "registry.example.com", Name: "app", Tag: "1", Digest: digest.FromString("app1"), Size: 150,
+			Created: testNow.Add(-48 * time.Hour), Blobs: []v1.Descriptor{base, blob("app1", 50)}},
+		{Hostname: "registry.example.com", Name: "app", Tag: "latest", Digest: digest.FromString("app1"), Size: 150,
+			Created: testNow.Add(-48 * time.Hour), Blobs: []v1.Descriptor{base, blob("app1", 50)}},
+		{Hostname: "registry.example.com", Name: "web", Tag: "1", Digest: digest.FromString("web1"), Size: 130,
+			Created: testNow.Add(-500 * 24 * time.Hour), Blobs: []v1.Descriptor{base, blob("web1", 30)}},
+		{Hostname: "registry.example.com", Name: "web", Tag: "sbom", Digest: digest.FromString("sbom"), Size: 10,
+			Blobs: []v1.Descriptor{blob("sbom", 10)}},
+	}
+
+	stats := RepoStatistics(images, testNow)
+	if len(stats) != 2 || stats[0].Name != "app" || stats[1].Name != "web" {
+		t.Fatalf("RepoStatistics() = %+v, want app then web", stats)
+	}
+
+	app := stats[0]
+	if app.Tags != 2 || app.Manifests != 1 || app.Size != 300 || app.UniqueSize != 150 {
+		t.Errorf("app stats = %+v, want 2 tags of one 150 byte manifest", app)
+	}
+	if want := []int{2, 0, 0, 0, 0, 0}; !reflect.DeepEqual(app.Ages, want) {
+		t.Errorf("app ages = %v, want %v", app.Ages, want)
+	}
+
+	// the artifact has no creation time, so it is neither the oldest nor the newest
+	web := stats[1]
+	if web.Oldest.Tag != "1" || web.Newest.Tag != "1" {
+		t.Errorf("web oldest %q and newest %q, want 1", web.Oldest.Tag, web.Newest.Tag)
+	}
+	if want := []int{0, 0, 0, 0, 1, 1}; !reflect.DeepEqual(web.Ages, want) {
+		t.Errorf("web ages = %v, want %v", web.Ages, want)
+	}
+
+	// the base layer is counted once across repositories
+	total := TotalStatistics(images, testNow)
+	if total.Tags != 4 || total.Manifests != 3 || total.Size != 440 || total.UniqueSize != 190 {
+		t.Errorf("total stats = %+v, want 4 tags, 3 manifests, 440 bytes, 190 unique", total)
+	}
+}
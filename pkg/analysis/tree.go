@@ -0,0 +1,123 @@
+package analysis
+
+import (
+	"sort"
+	"strings"
+
+	digest "github.com/opencontainers/go-digest"
+	"github.com/zawachte-msft/bupkis/pkg/registry"
+)
+
+// TreeNode is a registry, a path segment or a repository in the namespace
+// tree. A path segment can be a repository at the same time, such as team-a
+// next to team-a/web.
+type TreeNode struct {
+	Name string
+	// Repository is the full name of the repository the node stands for, if any
+	Repository string
+	// Tags are the tags of the repository the node stands for
+	Tags []string
+	// Images are the images of the repository, when the tree was built from images
+	Images []registry.ImageData
+	// TagCount counts the tags of the node and everything below it
+	TagCount int
+	// Size counts each blob below the node once
+	Size     int64
+	Children []*TreeNode
+
+	blobs map[digest.Digest]int64
+}
+
+// BuildTree arranges images by registry and repository path, with tag counts
+// and sizes summed up for every node. Roots are sorted by hostname.
+func BuildTree(images []registry.ImageData) []*TreeNode {
+
+	roots := []*TreeNode{}
+	for _, image := range images {
+		for _, node := range treePath(&roots, image.Hostname, image.Name) {
+			node.TagCount++
+			for _, blob := range image.Blobs {
+				node.blobs[blob.Digest] = blob.Size
+			}
+		}
+
+		repository := treeNode(&roots, image.Hostname, image.Name)
+		repository.Tags = append(repository.Tags, image.Tag)
+		repository.Images = append(repository.Images, image)
+	}
+
+	sortTree(roots)
+	return roots
+}
+
+// BuildRepoTree arranges repositories listed without their manifests, so the
+// nodes only count tags.
+func BuildRepoTree(repos []registry.RepoData) []*TreeNode {
+
+	roots := []*TreeNode{}
+	for _, repo := range repos {
+		for _, node := range treePath(&roots, repo.Hostname, repo.Name) {
+			node.TagCount += len(repo.Tags)
+		}
+
+		repository := treeNode(&roots, repo.Hostname, repo.Name)
+		repository.Tags = append(repository.Tags, repo.Tags...)
+	}
+
+	sortTree(roots)
+	return roots
+}
+
+// treePath returns the nodes from the registry down to the repository, creating missing ones
+func treePath(roots *[]*TreeNode, hostname string, repo string) []*TreeNode {
+
+	path := []*TreeNode{}
+	children := roots
+	segments := append([]string{hostname}, strings.Split(repo, "/")...)
+
+	for i, segment := range segments {
+		var node *TreeNode
+		for _, child := range *children {
+			if child.Name == segment {
+				node = child
+				break
+			}
+		}
+		if node == nil {
+			node = &TreeNode{Name: segment, blobs: map[digest.Digest]int64{}}
+			*children = append(*children, node)
+		}
+		if i == len(segments)-1 {
+			node.Repository = repo
+		}
+
+		path = append(path, node)
+		children = &node.Children
+	}
+
+	return path
+}
+
+func treeNode(roots *[]*TreeNode, hostname string, repo string) *TreeNode {
+	path := treePath(roots, hostname, repo)
+	return path[len(path)-1]
+}
+
+func sortTree(nodes []*TreeNode) {
+
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Name < nodes[j].Name
+	})
+
+	for _, node := range nodes {
+		node.Size = 0
+		for _, size := range node.blobs {
+			node.Size += size
+		}
+		sort.Strings(node.Tags)
+		sort.Slice(node.Images, func(i, j int) bool {
+			return node.Images[i].Tag < node.Images[j].Tag
+		})
+		sortTree(node.Children)
+	}
+}
@@ -0,0 +1,76 @@
+package analysis
+
+import (
+	"reflect"
+	"testing"
+
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/zawachte-msft/bupkis/pkg/registry"
+)
+
+// flattenTree lists the nodes depth first as depth, name, repository, tag count and size
+func flattenTree(nodes []*TreeNode, depth int) [][]interface{} {
+	rows := [][]interface{}{}
+	for _, node := range nodes {
+		rows = append(rows, []interface{}{depth, node.Name, node.Repository, node.TagCount, node.Size})
+		rows = append(rows, flattenTree(node.Children, depth+1)...)
+	}
+	return rows
+}
+
+func TestBuildTree(t *testing.T) {
+
+	base, web, api := blob("base", 100), blob("web", 10), blob("api", 20)
+	images := []registry.ImageData{
+		{Hostname: "b.example.com", Name: "tool", Tag: "1", Blobs: []v1.Descriptor{base}},
+		{Hostname: "a.example.com", Name: "team-a/web", Tag: "2", Blobs: []v1.Descriptor{base, web}},
+		{Hostname: "a.example.com", Name: "team-a/web", Tag: "1", Blobs: []v1.Descriptor{base, web}},
+		{Hostname: "a.example.com", Name: "team-a/api", Tag: "1", Blobs: []v1.Descriptor{base, api}},
+		{Hostname: "a.example.com", Name: "team-a", Tag: "1", Blobs: []v1.Descriptor{base}},
+	}
+
+	roots := BuildTree(images)
+
+	// team-a is a repository and the parent of team-a/web at the same time,
+	// and the base layer is counted once per node
+	want := [][]interface{}{
+		{0, "a.example.com", "", 4, int64(130)},
+		{1, "team-a", "team-a", 4, int64(130)},
+		{2, "api", "team-a/api", 1, int64(120)},
+		{2, "web", "team-a/web", 2, int64(110)},
+		{0, "b.example.com", "", 1, int64(100)},
+		{1, "tool", "tool", 1, int64(100)},
+	}
+	if got := flattenTree(roots, 0); !reflect.DeepEqual(got, want) {
+		t.Errorf("BuildTree() = %v, want %v", got, want)
+	}
+
+	webNode := roots[0].Children[0].Children[1]
+	if !reflect.DeepEqual(webNode.Tags, []string{"1", "2"}) || len(webNode.Images) != 2 || webNode.Images[0].Tag != "1" {
+		t.Errorf("team-a/web tags %v and images %+v, want 1 and 2 in order", webNode.Tags, webNode.Images)
+	}
+}
+
+func TestBuildRepoTree(t *testing.T) {
+
+	repos := []registry.RepoData{
+		{Hostname: "a.example.com", Name: "team-a/web", Tags: []string{"2", "1"}},
+		{Hostname: "a.example.com", Name: "team-a/api", Tags: []string{"1"}},
+		{Hostname: "a.example.com", Name: "empty"},
+	}
+
+	want := [][]interface{}{
+		{0, "a.example.com", "", 3, int64(0)},
+		{1, "empty", "empty", 0, int64(0)},
+		{1, "team-a", "", 3, int64(0)},
+		{2, "api", "team-a/api", 1, int64(0)},
+		{2, "web", "team-a/web", 2, int64(0)},
+	}
+	roots := BuildRepoTree(repos)
+	if got := flattenTree(roots, 0); !reflect.DeepEqual(got, want) {
+		t.Errorf("BuildRepoTree() = %v, want %v", got, want)
+	}
+	if tags := roots[0].Children[1].Children[1].Tags; !reflect.DeepEqual(tags, []string{"1", "2"}) {
+		t.Errorf("team-a/web tags = %v, want 1 and 2", tags)
+	}
+}
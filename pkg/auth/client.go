@@ -19,9 +19,35 @@ type Client interface {
 	// Login logs in to a remote server identified by the hostname.
 	Login(ctx context.Context, hostname, username, secret string, insecure bool) error
 	// Logout logs out from a remote server identified by the hostname.
-	// Logout(ctx context.Context, hostname string) error
+	Logout(ctx context.Context, hostname string) error
 	// Resolver returns a new authenticated resolver.
 	Resolver(ctx context.Context, client *http.Client, plainHTTP bool) (remotes.Resolver, error)
 	Credential(hostname string) (string, string, error)
 	GetAllCredentials() (map[string]ctypes.AuthConfig, error)
+	// ListCredentials describes the stored credentials without their secrets.
+	ListCredentials() ([]CredentialInfo, error)
+}
+
+// Sources a credential can be stored in
+const (
+	SourceHelper   = "helper"
+	SourceStore    = "store"
+	SourceFile     = "file"
+	SourceSecret   = "secret"
+	SourceEnv      = "env"
+	SourceNetrc    = "netrc"
+	SourceProvider = "provider"
+)
+
+// CredentialInfo describes a stored credential without its secret
+type CredentialInfo struct {
+	// Hostname is empty for environment variables that do not name their registry
+	Hostname string
+	Username string
+	// IdentityToken is set when the credential is a token rather than a password
+	IdentityToken bool
+	// Source is where the credential is kept: SourceHelper, SourceStore, SourceFile, SourceSecret, SourceEnv, SourceNetrc or SourceProvider
+	Source string
+	// Location names the credential helper, the credential store, the config file or the credential provider
+	Location string
 }
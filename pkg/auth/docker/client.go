@@ -1,20 +1,67 @@
 package docker
 
 import (
+	"fmt"
+	"os"
+
 	"github.com/zawachte-msft/bupkis/pkg/auth"
+	"github.com/zawachte-msft/bupkis/pkg/auth/provider"
 
 	"github.com/docker/cli/cli/config"
 	"github.com/docker/cli/cli/config/configfile"
 	"github.com/docker/cli/cli/config/credentials"
+	ctypes "github.com/docker/cli/cli/config/types"
 )
 
 // Client provides authentication operations for docker registries.
+//
+// Credentials are looked up in this order, the first match wins:
+//  1. Kubernetes image pull secrets given in ClientOptions.PullSecrets
+//  2. BUPKIS_AUTH_<HOST>_USERNAME and _PASSWORD, then the BUPKIS_AUTH JSON
+//  3. the netrc file, NETRC or ~/.netrc
+//  4. the credential providers in ClientOptions.CredentialProviderConfig
+//  5. the docker config, including its credential store and helpers
+//  6. the containers auth.json of podman, buildah and skopeo
 type Client struct {
+	// configs are searched for credentials in order
 	configs []*configfile.ConfigFile
+	// primary is the docker config that logins and logouts change
+	primary          *configfile.ConfigFile
+	credentialHelper string
+	// sources are the auth.Source* of the configs that are not docker configs
+	sources map[*configfile.ConfigFile]string
+	// providers are asked for credentials where the SourceProvider config is in configs
+	providers *provider.Providers
+	// repository is sent to the providers
+	repository string
+	// envVariables name the environment variables of the SourceEnv credentials
+	envVariables map[string]string
+}
+
+// ClientOptions configures where the client stores credentials
+type ClientOptions struct {
+	// CredentialHelper is the suffix of a docker-credential-<helper> binary
+	// that logins store their credential in, instead of the configured store.
+	CredentialHelper string
+	// PullSecrets are Kubernetes image pull secret files, or directories of
+	// them, whose credentials take precedence over the docker config.
+	PullSecrets []string
+	// CredentialProviderConfig is a file listing credential provider
+	// binaries that are run to get short lived registry credentials.
+	CredentialProviderConfig string
+	// Repository is sent to the credential providers by commands that work
+	// on a single repository, so that they can return a credential scoped to
+	// it. It is empty when the credential is used for the whole registry.
+	Repository string
 }
 
 // NewClient
 func NewClient() (auth.Client, error) {
+	return NewClientWithOptions(ClientOptions{})
+}
+
+// NewClientWithOptions returns a client for the docker config, storing credentials as configured by options.
+func NewClientWithOptions(options ClientOptions) (auth.Client, error) {
 	cfg, err := config.Load(config.Dir())
 	if err != nil {
 		return nil, err
@@ -23,12 +70,107 @@ func NewClient() (auth.Client, error) {
 		cfg.CredentialsStore = credentials.DetectDefaultStore(cfg.CredentialsStore)
 	}
 
+	secretConfigs, err := loadPullSecrets(options.PullSecrets)
+	if err != nil {
+		return nil, err
+	}
+
+	envCfg, envVariables, err := loadEnvCredentials()
+	if err != nil {
+		return nil, err
+	}
+
+	netrcCfg, err := loadNetrc()
+	if err != nil {
+		return nil, err
+	}
+
+	providerCfg, providers, err := loadCredentialProviders(options.CredentialProviderConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	sources := map[*configfile.ConfigFile]string{envCfg: auth.SourceEnv}
+	for _, secretConfig := range secretConfigs {
+		sources[secretConfig] = auth.SourceSecret
+	}
+
+	configs := append(secretConfigs, envCfg)
+	if netrcCfg != nil {
+		sources[netrcCfg] = auth.SourceNetrc
+		configs = append(configs, netrcCfg)
+	}
+	if providerCfg != nil {
+		sources[providerCfg] = auth.SourceProvider
+		configs = append(configs, providerCfg)
+	}
+	configs = append(configs, cfg)
+	if containersCfg := loadContainersAuthFile(); containersCfg != nil {
+		configs = append(configs, containersCfg)
+	}
+
 	return &Client{
-		configs: []*configfile.ConfigFile{cfg},
+		configs:          configs,
+		primary:          cfg,
+		credentialHelper: options.CredentialHelper,
+		sources:          sources,
+		providers:        providers,
+		repository:       options.Repository,
+		envVariables:     envVariables,
 	}, nil
 
 }
 
 func (c *Client) primaryCredentialsStore(hostname string) credentials.Store {
-	return c.configs[0].GetCredentialsStore(hostname)
+	if c.credentialHelper != "" {
+		return credentials.NewNativeStore(c.primary, c.credentialHelper)
+	}
+	return c.primary.GetCredentialsStore(hostname)
+}
+
+// allCredentials reads the credentials of a config, asking the credential
+// providers for the config that stands for them.
+func (c *Client) allCredentials(cfg *configfile.ConfigFile) map[string]ctypes.AuthConfig {
+	if c.sources[cfg] == auth.SourceProvider {
+		return c.providerCredentials()
+	}
+	return allCredentials(cfg)
+}
+
+// allCredentials reads the credentials of the default store and of every
+// registry with its own credential helper. Unlike configfile.GetAllCredentials
+// a failing store or helper only loses its own registries.
+func allCredentials(cfg *configfile.ConfigFile) map[string]ctypes.AuthConfig {
+
+	returnMap := make(map[string]ctypes.AuthConfig)
+
+	localMap, err := cfg.GetCredentialsStore("").GetAll()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: reading credentials of %s: %v\n", cfg.Filename, err)
+	}
+	for hostname, authConfig := range localMap {
+		if isEmptyCredential(authConfig) {
+			continue
+		}
+		returnMap[hostname] = authConfig
+	}
+
+	// credentials from a registry specific helper override the default store
+	for hostname, helper := range cfg.CredentialHelpers {
+		authConfig, err := cfg.GetAuthConfig(hostname)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "WARNING: reading credentials of %s from docker-credential-%s: %v\n", hostname, helper, err)
+			continue
+		}
+		if isEmptyCredential(authConfig) {
+			continue
+		}
+		returnMap[hostname] = authConfig
+	}
+
+	return returnMap
+}
+
+func isEmptyCredential(authConfig ctypes.AuthConfig) bool {
+	return authConfig.Username == "" && authConfig.Password == "" && authConfig.IdentityToken == ""
 }
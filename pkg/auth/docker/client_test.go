@@ -0,0 +1,365 @@
+package docker
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/docker/cli/cli/config"
+	"github.com/zawachte-msft/bupkis/pkg/auth"
+)
+
+const testHostname = "registry.example.com"
+
+// credentialSources writes a credential for testHostname into each of the
+// given sources, with the source as username, and points the client at them.
+type credentialSources struct {
+	secret, env, netrc, provider, docker, podman bool
+}
+
+func setupCredentialSources(t *testing.T, sources credentialSources, hostname string) ClientOptions {
+
+	dir := t.TempDir()
+	options := ClientOptions{}
+
+	// keep the user's own credentials and caches out of the test
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(dir, "cache"))
+	t.Setenv("XDG_RUNTIME_DIR", "")
+	t.Setenv("BUPKIS_AUTH", "")
+	t.Setenv("NETRC", filepath.Join(dir, "netrc"))
+	t.Setenv("REGISTRY_AUTH_FILE", filepath.Join(dir, "auth.json"))
+
+	dockerDir := filepath.Join(dir, "docker")
+	oldDir := config.Dir()
+	config.SetDir(dockerDir)
+	t.Cleanup(func() { config.SetDir(oldDir) })
+
+	if sources.secret {
+		path := filepath.Join(dir, "secret.json")
+		dockerConfig := base64.StdEncoding.EncodeToString([]byte(authsJSON(hostname, "secret")))
+		writeFile(t, path, fmt.Sprintf(`{"kind": "Secret", "type": "kubernetes.io/dockerconfigjson", "data": {".dockerconfigjson": %q}}`, dockerConfig))
+		options.PullSecrets = []string{path}
+	}
+	if sources.env {
+		t.Setenv(envAuthPrefix+envHostKey(hostname)+"_USERNAME", "env")
+		t.Setenv(envAuthPrefix+envHostKey(hostname)+"_PASSWORD", "secret")
+		t.Setenv(envAuthPrefix+envHostKey(hostname)+"_REGISTRY", hostname)
+	}
+	if sources.netrc {
+		writeFile(t, filepath.Join(dir, "netrc"), fmt.Sprintf("machine %s login netrc password secret\n", hostname))
+	}
+	if sources.provider {
+		if runtime.GOOS == "windows" {
+			t.Skip("the test provider is a shell script")
+		}
+		script := filepath.Join(dir, "provider")
+		writeFile(t, script, "#!/bin/sh\ncat > /dev/null\necho '{\"username\": \"provider\", \"password\": \"secret\"}'\n")
+		if err := os.Chmod(script, 0700); err != nil {
+			t.Fatal(err)
+		}
+		options.CredentialProviderConfig = filepath.Join(dir, "providers.yaml")
+		writeFile(t, options.CredentialProviderConfig, fmt.Sprintf("providers:\n- name: test\n  command: %s\n  matchRegistries: [%q]\n", script, hostname))
+	}
+	if sources.docker {
+		writeFile(t, filepath.Join(dockerDir, "config.json"), authsJSON(hostname, "docker"))
+	} else {
+		// a config without credentials would detect the credential store of the machine
+		writeFile(t, filepath.Join(dockerDir, "config.json"), `{"auths": {}, "credsStore": ""}`)
+	}
+	if sources.podman {
+		writeFile(t, filepath.Join(dir, "auth.json"), authsJSON(hostname, "podman"))
+	}
+
+	return options
+}
+
+func authsJSON(hostname string, username string) string {
+	auth := base64.StdEncoding.EncodeToString([]byte(username + ":secret"))
+	return fmt.Sprintf(`{"auths": {%q: {"auth": %q}}}`, hostname, auth)
+}
+
+func writeFile(t *testing.T, path string, content string) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func credentialUsername(t *testing.T, options ClientOptions, hostname string) string {
+
+	client, err := NewClientWithOptions(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	username, _, err := client.Credential(hostname)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return username
+}
+
+// TestCredentialPrecedence checks the lookup order documented on Client and
+// in the README: each source wins over every source after it.
+func TestCredentialPrecedence(t *testing.T) {
+
+	tests := []struct {
+		sources credentialSources
+		want    string
+	}{
+		{credentialSources{secret: true, env: true, netrc: true, provider: true, docker: true, podman: true}, "secret"},
+		{credentialSources{env: true, netrc: true, provider: true, docker: true, podman: true}, "env"},
+		{credentialSources{netrc: true, provider: true, docker: true, podman: true}, "netrc"},
+		{credentialSources{provider: true, docker: true, podman: true}, "provider"},
+		{credentialSources{docker: true, podman: true}, "docker"},
+		{credentialSources{podman: true}, "podman"},
+		{credentialSources{}, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("want %q", test.want), func(t *testing.T) {
+			options := setupCredentialSources(t, test.sources, testHostname)
+			if got := credentialUsername(t, options, testHostname); got != test.want {
+				t.Errorf("Credential(%q) username = %q, want %q", testHostname, got, test.want)
+			}
+		})
+	}
+}
+
+// TestCredentialDockerHubAliases checks that credentials written by hand for
+// one name of Docker Hub are found by every other name.
+func TestCredentialDockerHubAliases(t *testing.T) {
+
+	for _, source := range []string{"env", "netrc"} {
+		for _, stored := range []string{"docker.io", "index.docker.io", "registry-1.docker.io"} {
+			for _, requested := range []string{"docker.io", "index.docker.io", "registry-1.docker.io"} {
+				t.Run(fmt.Sprintf("%s %s as %s", source, stored, requested), func(t *testing.T) {
+					options := setupCredentialSources(t, credentialSources{env: source == "env", netrc: source == "netrc"}, stored)
+					if got := credentialUsername(t, options, requested); got != source {
+						t.Errorf("Credential(%q) username = %q, want %q", requested, got, source)
+					}
+				})
+			}
+		}
+	}
+
+	t.Run("BUPKIS_AUTH", func(t *testing.T) {
+		options := setupCredentialSources(t, credentialSources{}, testHostname)
+		t.Setenv("BUPKIS_AUTH", `{"docker.io": {"username": "env", "password": "secret"}}`)
+		if got := credentialUsername(t, options, "registry-1.docker.io"); got != "env" {
+			t.Errorf("Credential(%q) username = %q, want %q", "registry-1.docker.io", got, "env")
+		}
+	})
+}
+
+// TestProviderRequest checks that the credential providers get the
+// repository of the client and serve Docker Hub by any of its names.
+func TestProviderRequest(t *testing.T) {
+
+	if runtime.GOOS == "windows" {
+		t.Skip("the test provider is a shell script")
+	}
+
+	options := setupCredentialSources(t, credentialSources{}, testHostname)
+
+	dir := t.TempDir()
+	request := filepath.Join(dir, "request.json")
+	script := filepath.Join(dir, "provider")
+	writeFile(t, script, fmt.Sprintf("#!/bin/sh\ncat > %s\necho '{\"username\": \"provider\", \"password\": \"secret\"}'\n", request))
+	if err := os.Chmod(script, 0700); err != nil {
+		t.Fatal(err)
+	}
+	options.CredentialProviderConfig = filepath.Join(dir, "providers.yaml")
+	writeFile(t, options.CredentialProviderConfig, fmt.Sprintf("providers:\n- name: hub\n  command: %s\n  matchRegistries: [docker.io]\n", script))
+	options.Repository = "library/alpine"
+
+	if got := credentialUsername(t, options, "registry-1.docker.io"); got != "provider" {
+		t.Fatalf("Credential(%q) username = %q, want %q", "registry-1.docker.io", got, "provider")
+	}
+
+	content, err := ioutil.ReadFile(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"registry":"docker.io","repository":"library/alpine"}`; string(content) != want {
+		t.Errorf("provider request = %s, want %s", content, want)
+	}
+}
+
+// TestProviderErrorFallsThrough checks that a failing credential provider
+// does not hide the credentials of the sources after it.
+func TestProviderErrorFallsThrough(t *testing.T) {
+
+	if runtime.GOOS == "windows" {
+		t.Skip("the test provider is a shell script")
+	}
+
+	options := setupCredentialSources(t, credentialSources{docker: true}, testHostname)
+
+	dir := t.TempDir()
+	script := filepath.Join(dir, "provider")
+	writeFile(t, script, "#!/bin/sh\ncat > /dev/null\nexit 1\n")
+	if err := os.Chmod(script, 0700); err != nil {
+		t.Fatal(err)
+	}
+	options.CredentialProviderConfig = filepath.Join(dir, "providers.yaml")
+	writeFile(t, options.CredentialProviderConfig, fmt.Sprintf("providers:\n- name: broken\n  command: %s\n  matchRegistries: [%q]\n", script, testHostname))
+
+	if got := credentialUsername(t, options, testHostname); got != "docker" {
+		t.Errorf("Credential(%q) username = %q, want %q", testHostname, got, "docker")
+	}
+
+	// without another source the provider error is reported
+	options = setupCredentialSources(t, credentialSources{}, testHostname)
+	options.CredentialProviderConfig = filepath.Join(dir, "providers.yaml")
+
+	client, err := NewClientWithOptions(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := client.Credential(testHostname); err == nil {
+		t.Errorf("Credential(%q) succeeded, want the provider error", testHostname)
+	}
+}
+
+// fakeCredentialHelper keeps a single credential in a file next to itself,
+// speaking the docker-credential-helpers protocol.
+const fakeCredentialHelper = `#!/bin/sh
+store="$(dirname "$0")/credential.json"
+case "$1" in
+store)
+	cat > "$store"
+	;;
+get)
+	read -r server
+	if [ ! -f "$store" ] || ! grep -q "\"ServerURL\":\"$server\"" "$store"; then
+		echo "credentials not found in native keychain"
+		exit 1
+	fi
+	cat "$store"
+	;;
+list)
+	if [ -f "$store" ]; then
+		sed 's/.*"ServerURL":"\([^"]*\)".*"Username":"\([^"]*\)".*/{"\1":"\2"}/' "$store"
+	else
+		echo "{}"
+	fi
+	;;
+erase)
+	rm -f "$store"
+	;;
+esac
+`
+
+// TestCredentialHelperRoundTrip logs in through a docker-credential-<helper>
+// on PATH, checks that the credential is read back and listed from it, and
+// logs out again.
+func TestCredentialHelperRoundTrip(t *testing.T) {
+
+	if runtime.GOOS == "windows" {
+		t.Skip("the test credential helper is a shell script")
+	}
+
+	setupCredentialSources(t, credentialSources{}, testHostname)
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "docker-credential-fake"), fakeCredentialHelper)
+	if err := os.Chmod(filepath.Join(dir, "docker-credential-fake"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	hostname := server.Listener.Addr().String()
+
+	client, err := NewClientWithOptions(ClientOptions{CredentialHelper: "fake"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Login(context.Background(), hostname, "helper", "secret", true); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := config.Load(config.Dir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if helper := cfg.CredentialHelpers[hostname]; helper != "fake" {
+		t.Errorf("credHelpers[%q] = %q, want %q", hostname, helper, "fake")
+	}
+	if !isEmptyCredential(cfg.AuthConfigs[hostname]) {
+		t.Errorf("credential of %s stored in the docker config instead of the helper", hostname)
+	}
+
+	// a client without the option finds the helper through credHelpers
+	client, err = NewClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	username, password, err := client.Credential(hostname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if username != "helper" || password != "secret" {
+		t.Errorf("Credential(%q) = %q, %q, want %q, %q", hostname, username, password, "helper", "secret")
+	}
+
+	all, err := client.GetAllCredentials()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if all[hostname].Username != "helper" {
+		t.Errorf("GetAllCredentials()[%q] username = %q, want %q", hostname, all[hostname].Username, "helper")
+	}
+
+	infos, err := client.ListCredentials()
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, info := range infos {
+		if info.Hostname != hostname {
+			continue
+		}
+		found = true
+		if info.Username != "helper" || info.Source != auth.SourceHelper || info.Location != "docker-credential-fake" {
+			t.Errorf("ListCredentials() describes %s as %+v", hostname, info)
+		}
+	}
+	if !found {
+		t.Errorf("ListCredentials() does not list %s", hostname)
+	}
+
+	if err := client.Logout(context.Background(), hostname); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err = config.Load(config.Dir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if helper, ok := cfg.CredentialHelpers[hostname]; ok {
+		t.Errorf("credHelpers[%q] = %q after logout, want it removed", hostname, helper)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "credential.json")); !os.IsNotExist(err) {
+		t.Errorf("credential of %s still in the helper after logout", hostname)
+	}
+	if err := client.Logout(context.Background(), hostname); err != auth.ErrNotLoggedIn {
+		t.Errorf("second Logout(%q) = %v, want %v", hostname, err, auth.ErrNotLoggedIn)
+	}
+}
@@ -0,0 +1,62 @@
+package docker
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/docker/cli/cli/config"
+	"github.com/docker/cli/cli/config/configfile"
+)
+
+// containersAuthFile returns the auth file podman, buildah and skopeo log in
+// to: REGISTRY_AUTH_FILE, the one in XDG_RUNTIME_DIR, or the one in the
+// user's config directory.
+func containersAuthFile() string {
+
+	if path := os.Getenv("REGISTRY_AUTH_FILE"); path != "" {
+		return path
+	}
+
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		path := filepath.Join(dir, "containers", "auth.json")
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "containers", "auth.json")
+}
+
+// loadContainersAuthFile reads the containers auth file, which shares the
+// auths section of the docker config. It returns nil if there is none.
+func loadContainersAuthFile() *configfile.ConfigFile {
+
+	path := containersAuthFile()
+	if path == "" {
+		return nil
+	}
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: reading credentials of %s: %v\n", path, err)
+		return nil
+	}
+	defer file.Close()
+
+	cfg, err := config.LoadFromReader(file)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: reading credentials of %s: %v\n", path, err)
+		return nil
+	}
+	cfg.Filename = path
+
+	return cfg
+}
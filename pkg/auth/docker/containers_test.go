@@ -0,0 +1,54 @@
+package docker
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestContainersAuthFile(t *testing.T) {
+
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("REGISTRY_AUTH_FILE", "")
+	t.Setenv("XDG_RUNTIME_DIR", filepath.Join(dir, "run"))
+
+	// the runtime file is only used when it exists
+	if got, want := containersAuthFile(), filepath.Join(dir, ".config", "containers", "auth.json"); got != want {
+		t.Errorf("containersAuthFile() = %q, want %q", got, want)
+	}
+
+	runtimeFile := filepath.Join(dir, "run", "containers", "auth.json")
+	writeFile(t, runtimeFile, authsJSON(testHostname, "podman"))
+	if got := containersAuthFile(); got != runtimeFile {
+		t.Errorf("containersAuthFile() = %q, want %q", got, runtimeFile)
+	}
+
+	t.Setenv("REGISTRY_AUTH_FILE", filepath.Join(dir, "auth.json"))
+	if got, want := containersAuthFile(), filepath.Join(dir, "auth.json"); got != want {
+		t.Errorf("containersAuthFile() = %q, want %q", got, want)
+	}
+}
+
+func TestLoadContainersAuthFile(t *testing.T) {
+
+	path := filepath.Join(t.TempDir(), "auth.json")
+	t.Setenv("REGISTRY_AUTH_FILE", path)
+
+	if cfg := loadContainersAuthFile(); cfg != nil {
+		t.Errorf("loadContainersAuthFile() = %+v without a file, want nil", cfg)
+	}
+
+	writeFile(t, path, "not json")
+	if cfg := loadContainersAuthFile(); cfg != nil {
+		t.Errorf("loadContainersAuthFile() = %+v for an invalid file, want nil", cfg)
+	}
+
+	writeFile(t, path, authsJSON(testHostname, "podman"))
+	cfg := loadContainersAuthFile()
+	if cfg == nil {
+		t.Fatal("loadContainersAuthFile() = nil, want the credentials of the file")
+	}
+	if cfg.Filename != path || cfg.AuthConfigs[testHostname].Username != "podman" {
+		t.Errorf("loadContainersAuthFile() read %s as %+v", cfg.Filename, cfg.AuthConfigs)
+	}
+}
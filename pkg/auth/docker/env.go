@@ -0,0 +1,166 @@
+package docker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/docker/cli/cli/config"
+	"github.com/docker/cli/cli/config/configfile"
+	ctypes "github.com/docker/cli/cli/config/types"
+	"github.com/zawachte-msft/bupkis/pkg/auth"
+)
+
+const (
+	// envAuth holds credentials of several registries as JSON, in the format
+	// of the auths section of the docker config, with or without the section
+	envAuth = "BUPKIS_AUTH"
+	// envAuthPrefix starts BUPKIS_AUTH_<HOST>_USERNAME, _PASSWORD and _REGISTRY
+	envAuthPrefix = "BUPKIS_AUTH_"
+)
+
+// envAuthRegexp splits BUPKIS_AUTH_<HOST>_<FIELD> variables
+var envAuthRegexp = regexp.MustCompile(`^BUPKIS_AUTH_([A-Z0-9_]+)_(USERNAME|PASSWORD|REGISTRY)$`)
+
+// nonAlphanumericRegexp matches the characters of a hostname that are replaced in variable names
+var nonAlphanumericRegexp = regexp.MustCompile(`[^A-Z0-9]`)
+
+// envHostKey is the <HOST> part of the variables of a hostname, e.g.
+// MYREGISTRY_AZURECR_IO for myregistry.azurecr.io
+func envHostKey(hostname string) string {
+	return nonAlphanumericRegexp.ReplaceAllString(strings.ToUpper(hostname), "_")
+}
+
+// envHostVariables groups the BUPKIS_AUTH_<HOST>_* variables by <HOST>
+func envHostVariables() map[string]map[string]string {
+
+	hosts := map[string]map[string]string{}
+	for _, variable := range os.Environ() {
+		name := strings.SplitN(variable, "=", 2)[0]
+		if !strings.HasPrefix(name, envAuthPrefix) {
+			continue
+		}
+
+		match := envAuthRegexp.FindStringSubmatch(name)
+		if match == nil {
+			continue
+		}
+		if hosts[match[1]] == nil {
+			hosts[match[1]] = map[string]string{}
+		}
+		hosts[match[1]][match[2]] = os.Getenv(name)
+	}
+
+	return hosts
+}
+
+// loadEnvCredentials collects the credentials in the environment whose
+// registry is known: those of BUPKIS_AUTH, and those whose registry is set
+// by BUPKIS_AUTH_<HOST>_REGISTRY. A variable name cannot be turned back into
+// a hostname, as hyphens and ports are lost, so the other variables are only
+// found by envCredential. The returned map names the variables each
+// hostname's credential comes from.
+func loadEnvCredentials() (*configfile.ConfigFile, map[string]string, error) {
+
+	cfg := configfile.New("environment")
+	variables := map[string]string{}
+
+	if value := os.Getenv(envAuth); value != "" {
+		auths, err := parseEnvAuth(value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid %s: %v", envAuth, err)
+		}
+		for hostname, authConfig := range auths {
+			cfg.AuthConfigs[hostname] = authConfig
+			variables[hostname] = envAuth
+		}
+	}
+
+	for key, fields := range envHostVariables() {
+		hostname := fields["REGISTRY"]
+		if hostname == "" || (fields["USERNAME"] == "" && fields["PASSWORD"] == "") {
+			continue
+		}
+
+		cfg.AuthConfigs[hostname] = envAuthConfig(hostname, fields["USERNAME"], fields["PASSWORD"])
+		variables[hostname] = envAuthPrefix + key + "_*"
+	}
+
+	return cfg, variables, nil
+}
+
+// envCredential looks up the BUPKIS_AUTH_<HOST>_* variables of the hostname
+// by turning the hostname into <HOST>. Variables whose _REGISTRY names
+// another hostname are left alone.
+func envCredential(hostname string) (ctypes.AuthConfig, bool) {
+
+	fields := envHostVariables()[envHostKey(hostname)]
+	if fields["USERNAME"] == "" && fields["PASSWORD"] == "" {
+		return ctypes.AuthConfig{}, false
+	}
+	if fields["REGISTRY"] != "" && fields["REGISTRY"] != hostname {
+		return ctypes.AuthConfig{}, false
+	}
+
+	return envAuthConfig(hostname, fields["USERNAME"], fields["PASSWORD"]), true
+}
+
+// listUnresolvedEnvCredentials describes the BUPKIS_AUTH_<HOST>_* variables
+// without _REGISTRY by their variable names, as their hostname is unknown
+// until one is looked up.
+func listUnresolvedEnvCredentials() []auth.CredentialInfo {
+
+	infos := []auth.CredentialInfo{}
+	for key, fields := range envHostVariables() {
+		if fields["REGISTRY"] != "" || (fields["USERNAME"] == "" && fields["PASSWORD"] == "") {
+			continue
+		}
+
+		infos = append(infos, auth.CredentialInfo{
+			Username:      fields["USERNAME"],
+			IdentityToken: fields["USERNAME"] == "",
+			Source:        auth.SourceEnv,
+			Location:      envAuthPrefix + key + "_*",
+		})
+	}
+
+	return infos
+}
+
+// envAuthConfig treats a password without a username as an identity token,
+// the way logins store it.
+func envAuthConfig(hostname string, username string, password string) ctypes.AuthConfig {
+	if username == "" {
+		return ctypes.AuthConfig{ServerAddress: hostname, IdentityToken: password}
+	}
+	return ctypes.AuthConfig{ServerAddress: hostname, Username: username, Password: password}
+}
+
+// parseEnvAuth reads BUPKIS_AUTH through the docker config parser, so the
+// base64 encoded auth fields docker writes are understood too.
+func parseEnvAuth(value string) (map[string]ctypes.AuthConfig, error) {
+
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal([]byte(value), &fields); err != nil {
+		return nil, err
+	}
+
+	content := []byte(value)
+	if _, ok := fields["auths"]; !ok {
+		wrapped, err := json.Marshal(map[string]json.RawMessage{"auths": json.RawMessage(value)})
+		if err != nil {
+			return nil, err
+		}
+		content = wrapped
+	}
+
+	cfg, err := config.LoadFromReader(bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+
+	return cfg.AuthConfigs, nil
+}
@@ -0,0 +1,89 @@
+package docker
+
+import (
+	"testing"
+
+	"github.com/zawachte-msft/bupkis/pkg/auth"
+)
+
+func TestEnvCredential(t *testing.T) {
+
+	options := setupCredentialSources(t, credentialSources{}, testHostname)
+
+	// neither the hyphen nor the port survive in the variable names
+	t.Setenv("BUPKIS_AUTH_MY_REGISTRY_EXAMPLE_COM_USERNAME", "hyphen")
+	t.Setenv("BUPKIS_AUTH_MY_REGISTRY_EXAMPLE_COM_PASSWORD", "secret")
+	t.Setenv("BUPKIS_AUTH_LOCALHOST_5000_PASSWORD", "token")
+	// a registry named by _REGISTRY is only found by that name
+	t.Setenv("BUPKIS_AUTH_NAMED_EXAMPLE_COM_USERNAME", "named")
+	t.Setenv("BUPKIS_AUTH_NAMED_EXAMPLE_COM_PASSWORD", "secret")
+	t.Setenv("BUPKIS_AUTH_NAMED_EXAMPLE_COM_REGISTRY", "named-example.com")
+
+	client, err := NewClientWithOptions(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		hostname string
+		username string
+		password string
+	}{
+		{"my-registry.example.com", "hyphen", "secret"},
+		{"localhost:5000", "", "token"},
+		{"named-example.com", "named", "secret"},
+		{"named.example.com", "", ""},
+		{"other.example.com", "", ""},
+	}
+	for _, test := range tests {
+		username, password, err := client.Credential(test.hostname)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if username != test.username || password != test.password {
+			t.Errorf("Credential(%q) = %q, %q, want %q, %q", test.hostname, username, password, test.username, test.password)
+		}
+	}
+
+	all, err := client.GetAllCredentials()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 1 || all["named-example.com"].Username != "named" {
+		t.Errorf("GetAllCredentials() = %v, want only the credential naming its registry", all)
+	}
+}
+
+func TestListEnvCredentials(t *testing.T) {
+
+	options := setupCredentialSources(t, credentialSources{}, testHostname)
+	t.Setenv("BUPKIS_AUTH_MY_REGISTRY_EXAMPLE_COM_USERNAME", "hyphen")
+	t.Setenv("BUPKIS_AUTH_MY_REGISTRY_EXAMPLE_COM_PASSWORD", "secret")
+	t.Setenv("BUPKIS_AUTH_REGISTRY_EXAMPLE_COM_5000_PASSWORD", "token")
+	t.Setenv("BUPKIS_AUTH_REGISTRY_EXAMPLE_COM_5000_REGISTRY", "registry.example.com:5000")
+	t.Setenv("BUPKIS_AUTH", `{"ghcr.io": {"username": "json", "password": "secret"}}`)
+
+	client, err := NewClientWithOptions(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	infos, err := client.ListCredentials()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []auth.CredentialInfo{
+		{Username: "hyphen", Source: auth.SourceEnv, Location: "BUPKIS_AUTH_MY_REGISTRY_EXAMPLE_COM_*"},
+		{Hostname: "ghcr.io", Username: "json", Source: auth.SourceEnv, Location: "BUPKIS_AUTH"},
+		{Hostname: "registry.example.com:5000", IdentityToken: true, Source: auth.SourceEnv, Location: "BUPKIS_AUTH_REGISTRY_EXAMPLE_COM_5000_*"},
+	}
+	if len(infos) != len(want) {
+		t.Fatalf("ListCredentials() = %+v, want %+v", infos, want)
+	}
+	for i := range want {
+		if infos[i] != want[i] {
+			t.Errorf("ListCredentials()[%d] = %+v, want %+v", i, infos[i], want[i])
+		}
+	}
+}
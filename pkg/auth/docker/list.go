@@ -0,0 +1,76 @@
+package docker
+
+import (
+	"sort"
+
+	"github.com/docker/cli/cli/config/configfile"
+	"github.com/zawachte-msft/bupkis/pkg/auth"
+)
+
+// ListCredentials describes the credentials of every config, sorted by
+// hostname. A hostname found in several configs is described by the first.
+// Environment variables whose hostname is unknown come first, without one.
+func (c *Client) ListCredentials() ([]auth.CredentialInfo, error) {
+
+	infos := []auth.CredentialInfo{}
+	seen := map[string]bool{}
+
+	for _, cfg := range c.configs {
+		if c.sources[cfg] == auth.SourceProvider {
+			// listing does not run the providers, their credentials are short lived anyway
+			for hostname, name := range c.providers.Registries() {
+				if seen[hostname] {
+					continue
+				}
+				seen[hostname] = true
+				infos = append(infos, auth.CredentialInfo{Hostname: hostname, Source: auth.SourceProvider, Location: name})
+			}
+			continue
+		}
+
+		for hostname, authConfig := range allCredentials(cfg) {
+			if seen[hostname] {
+				continue
+			}
+			seen[hostname] = true
+
+			source, location := credentialSource(cfg, hostname)
+			if c.sources[cfg] != "" {
+				source = c.sources[cfg]
+			}
+			if source == auth.SourceEnv {
+				location = c.envVariables[hostname]
+			}
+			infos = append(infos, auth.CredentialInfo{
+				Hostname:      hostname,
+				Username:      authConfig.Username,
+				IdentityToken: authConfig.IdentityToken != "",
+				Source:        source,
+				Location:      location,
+			})
+		}
+	}
+
+	infos = append(infos, listUnresolvedEnvCredentials()...)
+
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].Hostname == infos[j].Hostname {
+			return infos[i].Location < infos[j].Location
+		}
+		return infos[i].Hostname < infos[j].Hostname
+	})
+
+	return infos, nil
+}
+
+// credentialSource tells where the config keeps the credential of hostname,
+// the same way configfile.GetCredentialsStore picks it.
+func credentialSource(cfg *configfile.ConfigFile, hostname string) (string, string) {
+	if helper := cfg.CredentialHelpers[hostname]; helper != "" {
+		return auth.SourceHelper, "docker-credential-" + helper
+	}
+	if cfg.CredentialsStore != "" {
+		return auth.SourceStore, "docker-credential-" + cfg.CredentialsStore
+	}
+	return auth.SourceFile, cfg.Filename
+}
@@ -41,5 +41,18 @@ func (c *Client) Login(ctx context.Context, hostname, username, secret string, i
 	}
 
 	// Store credential
-	return c.primaryCredentialsStore(hostname).Store(ctypes.AuthConfig(cred))
+	if err := c.primaryCredentialsStore(hostname).Store(ctypes.AuthConfig(cred)); err != nil {
+		return err
+	}
+
+	// Remember the helper, so the credential is looked up where it was stored
+	if c.credentialHelper != "" && c.primary.CredentialHelpers[hostname] != c.credentialHelper {
+		if c.primary.CredentialHelpers == nil {
+			c.primary.CredentialHelpers = map[string]string{}
+		}
+		c.primary.CredentialHelpers[hostname] = c.credentialHelper
+		return c.primary.Save()
+	}
+
+	return nil
 }
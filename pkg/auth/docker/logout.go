@@ -0,0 +1,33 @@
+package docker
+
+import (
+	"context"
+
+	"github.com/zawachte-msft/bupkis/pkg/auth"
+)
+
+// Logout removes the credential of a docker registry identified by the hostname.
+func (c *Client) Logout(_ context.Context, hostname string) error {
+	hostname = resolveHostname(hostname)
+
+	store := c.primaryCredentialsStore(hostname)
+	cred, err := store.Get(hostname)
+	if err != nil {
+		return err
+	}
+	if cred.Username == "" && cred.Password == "" && cred.IdentityToken == "" {
+		return auth.ErrNotLoggedIn
+	}
+
+	if err := store.Erase(hostname); err != nil {
+		return err
+	}
+
+	// Forget the helper, so later logins are not sent to it
+	if _, ok := c.primary.CredentialHelpers[hostname]; ok {
+		delete(c.primary.CredentialHelpers, hostname)
+		return c.primary.Save()
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package docker
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/docker/cli/cli/config/configfile"
+	ctypes "github.com/docker/cli/cli/config/types"
+)
+
+// netrcPath returns the netrc file named by NETRC, or the one in the home directory
+func netrcPath() string {
+
+	if path := os.Getenv("NETRC"); path != "" {
+		return path
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	if runtime.GOOS == "windows" {
+		return filepath.Join(home, "_netrc")
+	}
+	return filepath.Join(home, ".netrc")
+}
+
+// loadNetrc reads the machine entries of the netrc file. The default entry is
+// ignored so that the credential is not sent to every registry. It returns
+// nil if there is no netrc file.
+func loadNetrc() (*configfile.ConfigFile, error) {
+
+	path := netrcPath()
+	if path == "" {
+		return nil, nil
+	}
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	cfg := configfile.New(path)
+
+	machine := ""
+	authConfig := ctypes.AuthConfig{}
+	flush := func() {
+		if machine != "" && (authConfig.Username != "" || authConfig.Password != "") {
+			authConfig.ServerAddress = machine
+			cfg.AuthConfigs[machine] = authConfig
+		}
+		machine = ""
+		authConfig = ctypes.AuthConfig{}
+	}
+
+	// tokens are read line by line, as a macro definition runs until an empty line
+	inMacro := false
+	tokens := []string{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if inMacro {
+			inMacro = strings.TrimSpace(line) != ""
+			continue
+		}
+		tokens = append(tokens, strings.Fields(line)...)
+
+		for len(tokens) != 0 {
+			token := tokens[0]
+			if token == "machine" || token == "login" || token == "password" || token == "account" {
+				// the value may be on the next line
+				if len(tokens) < 2 {
+					break
+				}
+			}
+
+			switch token {
+			case "machine":
+				flush()
+				machine = tokens[1]
+			case "default":
+				flush()
+			case "login":
+				authConfig.Username = tokens[1]
+			case "password":
+				authConfig.Password = tokens[1]
+			case "macdef":
+				flush()
+				inMacro = true
+				tokens = nil
+				continue
+			}
+
+			switch token {
+			case "machine", "login", "password", "account":
+				tokens = tokens[2:]
+			default:
+				tokens = tokens[1:]
+			}
+		}
+	}
+	flush()
+
+	return cfg, scanner.Err()
+}
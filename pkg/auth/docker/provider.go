@@ -0,0 +1,59 @@
+package docker
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/docker/cli/cli/config/configfile"
+	ctypes "github.com/docker/cli/cli/config/types"
+	"github.com/zawachte-msft/bupkis/pkg/auth/provider"
+)
+
+// loadCredentialProviders reads the credential provider configuration. The
+// returned config holds no credentials, it only marks where the providers
+// are asked in the lookup order.
+func loadCredentialProviders(path string) (*configfile.ConfigFile, *provider.Providers, error) {
+
+	if path == "" {
+		return nil, nil, nil
+	}
+
+	providers, err := provider.Load(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return configfile.New(path), providers, nil
+}
+
+// providerCredential asks the credential providers for a credential of the
+// repository of the client, or of the whole registry without one.
+func (c *Client) providerCredential(hostname string) (ctypes.AuthConfig, bool, error) {
+
+	response, ok, err := c.providers.Credential(hostname, c.repository)
+	if !ok || err != nil {
+		return ctypes.AuthConfig{}, ok, err
+	}
+
+	return envAuthConfig(hostname, response.Username, response.Password), true, nil
+}
+
+// providerCredentials asks the credential providers for the credentials of
+// the registries they name without wildcards. A failing provider only loses
+// its own registries.
+func (c *Client) providerCredentials() map[string]ctypes.AuthConfig {
+
+	returnMap := make(map[string]ctypes.AuthConfig)
+	for hostname := range c.providers.Registries() {
+		authConfig, ok, err := c.providerCredential(hostname)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "WARNING: reading credentials of %s: %v\n", hostname, err)
+			continue
+		}
+		if ok {
+			returnMap[hostname] = authConfig
+		}
+	}
+
+	return returnMap
+}
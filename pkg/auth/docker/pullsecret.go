@@ -0,0 +1,175 @@
+package docker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/docker/cli/cli/config"
+	"github.com/docker/cli/cli/config/configfile"
+	"sigs.k8s.io/yaml"
+)
+
+// Types of the Kubernetes secrets that hold registry credentials
+const (
+	secretTypeDockerConfigJSON = "kubernetes.io/dockerconfigjson"
+	secretTypeDockerConfig     = "kubernetes.io/dockercfg"
+)
+
+// yamlDocumentSeparator splits a file into its YAML documents
+var yamlDocumentSeparator = regexp.MustCompile(`(?m)^---\s*$`)
+
+// kubernetesSecret holds the fields of a Secret, or of a List of them as
+// printed by kubectl get secrets -o yaml
+type kubernetesSecret struct {
+	Kind     string `json:"kind"`
+	Type     string `json:"type"`
+	Metadata struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"metadata"`
+	// Data is base64 encoded, which []byte decodes
+	Data       map[string][]byte  `json:"data"`
+	StringData map[string]string  `json:"stringData"`
+	Items      []kubernetesSecret `json:"items"`
+}
+
+// loadPullSecrets reads the image pull secrets in the given files and
+// directories. Files given directly must hold at least one pull secret, while
+// other files and secrets in a directory are skipped.
+func loadPullSecrets(paths []string) ([]*configfile.ConfigFile, error) {
+
+	configs := []*configfile.ConfigFile{}
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if !info.IsDir() {
+			fileConfigs, err := loadPullSecretFile(path)
+			if err != nil {
+				return nil, err
+			}
+			if len(fileConfigs) == 0 {
+				return nil, fmt.Errorf("%s contains no %s secret", path, secretTypeDockerConfigJSON)
+			}
+			configs = append(configs, fileConfigs...)
+			continue
+		}
+
+		entries, err := ioutil.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		names := []string{}
+		for _, entry := range entries {
+			switch strings.ToLower(filepath.Ext(entry.Name())) {
+			case ".yaml", ".yml", ".json":
+				if !entry.IsDir() {
+					names = append(names, entry.Name())
+				}
+			}
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			fileConfigs, err := loadPullSecretFile(filepath.Join(path, name))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "WARNING: skipping %s: %v\n", filepath.Join(path, name), err)
+				continue
+			}
+			configs = append(configs, fileConfigs...)
+		}
+	}
+
+	return configs, nil
+}
+
+// loadPullSecretFile reads every pull secret in a YAML or JSON file
+func loadPullSecretFile(path string) ([]*configfile.ConfigFile, error) {
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	secrets := []kubernetesSecret{}
+	for _, document := range yamlDocumentSeparator.Split(string(content), -1) {
+		if strings.TrimSpace(document) == "" {
+			continue
+		}
+
+		secret := kubernetesSecret{}
+		if err := yaml.Unmarshal([]byte(document), &secret); err != nil {
+			return nil, err
+		}
+		secrets = append(secrets, secret)
+		secrets = append(secrets, secret.Items...)
+	}
+
+	configs := []*configfile.ConfigFile{}
+	for _, secret := range secrets {
+		dockerConfig, err := secretDockerConfig(secret)
+		if err != nil {
+			return nil, fmt.Errorf("secret %s/%s: %v", secret.Metadata.Namespace, secret.Metadata.Name, err)
+		}
+		if dockerConfig == nil {
+			continue
+		}
+
+		cfg, err := config.LoadFromReader(bytes.NewReader(dockerConfig))
+		if err != nil {
+			return nil, fmt.Errorf("secret %s/%s: %v", secret.Metadata.Namespace, secret.Metadata.Name, err)
+		}
+		cfg.Filename = fmt.Sprintf("%s (%s)", path, secretName(secret))
+		// pull secrets are only read, never written to a credential store
+		cfg.CredentialsStore = ""
+		cfg.CredentialHelpers = nil
+
+		configs = append(configs, cfg)
+	}
+
+	return configs, nil
+}
+
+// secretDockerConfig returns the docker config held by a pull secret, or nil for other secrets
+func secretDockerConfig(secret kubernetesSecret) ([]byte, error) {
+
+	if secret.Kind != "Secret" {
+		return nil, nil
+	}
+
+	value := func(key string) []byte {
+		if stringValue, ok := secret.StringData[key]; ok {
+			return []byte(stringValue)
+		}
+		return secret.Data[key]
+	}
+
+	switch secret.Type {
+	case secretTypeDockerConfigJSON:
+		return value(".dockerconfigjson"), nil
+
+	case secretTypeDockerConfig:
+		// the legacy format is the auths section on its own
+		auths := json.RawMessage(value(".dockercfg"))
+		return json.Marshal(map[string]json.RawMessage{"auths": auths})
+	}
+
+	return nil, nil
+}
+
+func secretName(secret kubernetesSecret) string {
+	if secret.Metadata.Namespace == "" {
+		return secret.Metadata.Name
+	}
+	return secret.Metadata.Namespace + "/" + secret.Metadata.Name
+}
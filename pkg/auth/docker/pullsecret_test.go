@@ -0,0 +1,109 @@
+package docker
+
+import (
+	"encoding/base64"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadPullSecretFile(t *testing.T) {
+
+	dockerConfig := base64.StdEncoding.EncodeToString([]byte(authsJSON("a.example.com", "json")))
+	dockercfg := base64.StdEncoding.EncodeToString([]byte(`{"b.example.com": {"auth": "` + base64.StdEncoding.EncodeToString([]byte("legacy:secret")) + `"}}`))
+
+	// kubectl get secrets -o yaml prints a List, and files may hold several documents
+	path := filepath.Join(t.TempDir(), "secrets.yaml")
+	writeFile(t, path, fmt.Sprintf(`apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: Secret
+  type: kubernetes.io/dockerconfigjson
+  metadata:
+    name: json
+    namespace: team-a
+  data:
+    .dockerconfigjson: %s
+- apiVersion: v1
+  kind: Secret
+  type: Opaque
+  metadata:
+    name: other
+  data:
+    password: c2VjcmV0
+---
+apiVersion: v1
+kind: Secret
+type: kubernetes.io/dockercfg
+metadata:
+  name: legacy
+data:
+  .dockercfg: %s
+---
+apiVersion: v1
+kind: Secret
+type: kubernetes.io/dockerconfigjson
+metadata:
+  name: string-data
+stringData:
+  .dockerconfigjson: '%s'
+`, dockerConfig, dockercfg, authsJSON("c.example.com", "string")))
+
+	configs, err := loadPullSecretFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		filename string
+		hostname string
+		username string
+	}{
+		{path + " (team-a/json)", "a.example.com", "json"},
+		{path + " (legacy)", "b.example.com", "legacy"},
+		{path + " (string-data)", "c.example.com", "string"},
+	}
+	if len(configs) != len(want) {
+		t.Fatalf("loadPullSecretFile() read %d pull secrets, want %d", len(configs), len(want))
+	}
+	for i, w := range want {
+		if configs[i].Filename != w.filename || configs[i].AuthConfigs[w.hostname].Username != w.username {
+			t.Errorf("pull secret %d is %s with %+v, want %s with %s for %s", i, configs[i].Filename, configs[i].AuthConfigs, w.filename, w.username, w.hostname)
+		}
+	}
+}
+
+func TestLoadPullSecrets(t *testing.T) {
+
+	dir := t.TempDir()
+	secret := func(hostname string, username string) string {
+		dockerConfig := base64.StdEncoding.EncodeToString([]byte(authsJSON(hostname, username)))
+		return fmt.Sprintf(`{"kind": "Secret", "type": "kubernetes.io/dockerconfigjson", "metadata": {"name": %q}, "data": {".dockerconfigjson": %q}}`, username, dockerConfig)
+	}
+
+	secrets := filepath.Join(dir, "secrets")
+	writeFile(t, filepath.Join(secrets, "b.json"), secret("b.example.com", "b"))
+	writeFile(t, filepath.Join(secrets, "a.yaml"), secret("a.example.com", "a"))
+	writeFile(t, filepath.Join(secrets, "broken.yaml"), "kind: [")
+	writeFile(t, filepath.Join(secrets, "notes.txt"), "not a secret")
+	writeFile(t, filepath.Join(dir, "opaque.yaml"), `{"kind": "Secret", "type": "Opaque"}`)
+
+	// files in a directory are read in order, and unreadable ones are skipped
+	configs, err := loadPullSecrets([]string{secrets})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(configs) != 2 || configs[0].AuthConfigs["a.example.com"].Username != "a" || configs[1].AuthConfigs["b.example.com"].Username != "b" {
+		t.Errorf("loadPullSecrets() = %d configs, want the secrets of a.yaml and b.json", len(configs))
+	}
+
+	// a file given directly must hold a pull secret
+	if _, err := loadPullSecrets([]string{filepath.Join(dir, "opaque.yaml")}); err == nil || !strings.Contains(err.Error(), "no kubernetes.io/dockerconfigjson secret") {
+		t.Errorf("loadPullSecrets() of a file without pull secrets = %v, want an error", err)
+	}
+	if _, err := loadPullSecrets([]string{filepath.Join(dir, "missing.yaml")}); err == nil {
+		t.Error("loadPullSecrets() of a missing file succeeded")
+	}
+}
@@ -2,12 +2,16 @@ package docker
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+	"os"
 
 	"github.com/containerd/containerd/remotes"
 	"github.com/containerd/containerd/remotes/docker"
+	"github.com/docker/cli/cli/config/configfile"
 	ctypes "github.com/docker/cli/cli/config/types"
 	"github.com/docker/docker/registry"
+	authapi "github.com/zawachte-msft/bupkis/pkg/auth"
 )
 
 // Resolver returns a new authenticated resolver.
@@ -21,41 +25,93 @@ func (c *Client) Resolver(_ context.Context, client *http.Client, plainHTTP bool
 
 // Credential returns the login credential of the request host.
 func (c *Client) Credential(hostname string) (string, string, error) {
-	hostname = resolveHostname(hostname)
 	var (
 		auth ctypes.AuthConfig
 		err  error
+		// providerErr is returned when no other source has a credential either
+		providerErr error
 	)
 	for _, cfg := range c.configs {
-		auth, err = cfg.GetAuthConfig(hostname)
-		if err != nil {
+		found := false
+		for _, name := range c.lookupNames(cfg, hostname) {
+			if c.sources[cfg] == authapi.SourceProvider {
+				var ok bool
+				auth, ok, err = c.providerCredential(name)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "WARNING: reading credentials of %s: %v\n", hostname, err)
+					providerErr = err
+					continue
+				}
+				if !ok {
+					continue
+				}
+			} else {
+				auth, err = cfg.GetAuthConfig(name)
+			}
+			if envAuth, ok := envCredential(name); ok && c.sources[cfg] == authapi.SourceEnv {
+				// the variables of a hostname with a port are only found by name
+				auth, err = envAuth, nil
+			}
+			if err == nil && !isEmptyCredential(auth) {
+				found = true
+				break
+			}
+		}
+		if !found {
 			// fall back to next config
 			continue
 		}
 		if auth.IdentityToken != "" {
 			return "", auth.IdentityToken, nil
 		}
-		if auth.Username == "" && auth.Password == "" {
-			// fall back to next config
-			continue
-		}
 		return auth.Username, auth.Password, nil
 	}
+	if providerErr != nil {
+		return "", "", providerErr
+	}
 	return "", "", err
 }
 
+// lookupNames are the names the credential of the hostname is looked up by in
+// a config. Docker configs and pull secrets keep the Docker Hub credential
+// under https://index.docker.io/v1/, while the names of the environment, the
+// netrc file and the provider configuration are written by hand, so there
+// each of its names is tried, the given one first.
+func (c *Client) lookupNames(cfg *configfile.ConfigFile, hostname string) []string {
+
+	resolved := resolveHostname(hostname)
+	switch c.sources[cfg] {
+	case authapi.SourceEnv, authapi.SourceNetrc, authapi.SourceProvider:
+	default:
+		return []string{resolved}
+	}
+	if resolved != registry.IndexServer {
+		return []string{hostname}
+	}
+
+	names := []string{hostname}
+	for _, alias := range []string{registry.IndexName, registry.IndexHostname, registry.DefaultV2Registry.Host, registry.IndexServer} {
+		if alias != hostname {
+			names = append(names, alias)
+		}
+	}
+
+	return names
+}
+
+// GetAllCredentials returns the credentials of every config. Like
+// Credential, the first config with a credential for a hostname wins.
 func (c *Client) GetAllCredentials() (map[string]ctypes.AuthConfig, error) {
 
 	returnMap := make(map[string]ctypes.AuthConfig)
 	for _, cfg := range c.configs {
 
-		localMap, err := cfg.GetAllCredentials()
-		if err != nil {
-			// fall back to next config
-			continue
-		}
+		localMap := c.allCredentials(cfg)
 
 		for hostname, authConfig := range localMap {
+			if _, ok := returnMap[hostname]; ok {
+				continue
+			}
 			returnMap[hostname] = authConfig
 		}
 	}
@@ -0,0 +1,115 @@
+package provider
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// cache keeps provider credentials until they expire, in memory and in a
+// file only the user can read, so that separate bupkis runs share them.
+type cache struct {
+	mu      sync.Mutex
+	path    string
+	entries map[string]Response
+}
+
+func newCache() *cache {
+
+	c := &cache{entries: map[string]Response{}}
+
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return c
+	}
+	c.path = filepath.Join(dir, "bupkis", "credential-providers.json")
+
+	content, err := ioutil.ReadFile(c.path)
+	if err != nil {
+		return c
+	}
+	// a damaged cache is only a reason to ask the providers again
+	if err := json.Unmarshal(content, &c.entries); err != nil {
+		c.entries = map[string]Response{}
+	}
+	c.dropExpired()
+
+	return c
+}
+
+// dropExpired removes the expired entries, and the entries whose expiry is
+// unknown because another program wrote them
+func (c *cache) dropExpired() {
+	now := time.Now()
+	for key, entry := range c.entries {
+		if entry.ExpiresAt == nil || now.After(*entry.ExpiresAt) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+func (c *cache) get(key string) (Response, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	response, ok := c.entries[key]
+	// leave some time for the credential to be used
+	if !ok || response.ExpiresAt == nil || time.Now().Add(30*time.Second).After(*response.ExpiresAt) {
+		return Response{}, false
+	}
+
+	return response, true
+}
+
+func (c *cache) put(key string, response Response) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[key] = response
+	c.dropExpired()
+
+	if c.path != "" {
+		c.save()
+	}
+}
+
+// save writes the cache, ignoring errors as the cache only saves provider calls
+func (c *cache) save() {
+
+	// the directory may exist already, created by a program with a wider umask
+	if err := os.MkdirAll(filepath.Dir(c.path), 0700); err != nil {
+		return
+	}
+	if err := os.Chmod(filepath.Dir(c.path), 0700); err != nil {
+		return
+	}
+
+	content, err := json.Marshal(c.entries)
+	if err != nil {
+		return
+	}
+
+	file, err := ioutil.TempFile(filepath.Dir(c.path), "credential-providers")
+	if err != nil {
+		return
+	}
+	defer os.Remove(file.Name())
+
+	// set the mode before writing, so the secrets are never readable by others
+	if err := file.Chmod(0600); err != nil {
+		file.Close()
+		return
+	}
+	if _, err := file.Write(content); err != nil {
+		file.Close()
+		return
+	}
+	if err := file.Close(); err != nil {
+		return
+	}
+
+	os.Rename(file.Name(), c.path)
+}
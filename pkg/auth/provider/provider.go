@@ -0,0 +1,223 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// defaultCacheDuration applies to credentials returned without an expiry
+	defaultCacheDuration = 5 * time.Minute
+	// execTimeout bounds how long a provider may take to answer
+	execTimeout = time.Minute
+)
+
+// Config lists the credential providers, read from a YAML or JSON file
+type Config struct {
+	Providers []ProviderConfig `json:"providers"`
+}
+
+// ProviderConfig describes a credential provider binary and the registries it serves
+type ProviderConfig struct {
+	// Name identifies the provider in the cache and in messages
+	Name string `json:"name"`
+	// Command is the path of the binary, or its name on PATH
+	Command string   `json:"command"`
+	Args    []string `json:"args,omitempty"`
+	// Env is added to the environment of the binary
+	Env map[string]string `json:"env,omitempty"`
+	// MatchRegistries are hostnames or globs such as *.azurecr.io. Only
+	// hostnames without wildcards are included when listing every registry.
+	MatchRegistries []string `json:"matchRegistries"`
+	// DefaultCacheDuration applies when the response has no expiresAt, e.g. 10m
+	DefaultCacheDuration string `json:"defaultCacheDuration,omitempty"`
+}
+
+// Request is written to the stdin of the provider. Repository is empty when
+// the credential is used for the whole registry.
+type Request struct {
+	Registry   string `json:"registry"`
+	Repository string `json:"repository,omitempty"`
+}
+
+// Response is read from the stdout of the provider. A password without a
+// username is used as an identity token.
+type Response struct {
+	Username  string     `json:"username"`
+	Password  string     `json:"password"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// Providers runs the configured credential providers, caching their
+// credentials until they expire.
+type Providers struct {
+	providers []ProviderConfig
+	cache     *cache
+}
+
+// Load reads the provider configuration. The cached credentials are kept in
+// the bupkis directory of the user cache directory.
+func Load(configPath string) (*Providers, error) {
+
+	content, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	config := Config{}
+	if err := yaml.UnmarshalStrict(content, &config); err != nil {
+		return nil, fmt.Errorf("invalid credential provider configuration %s: %v", configPath, err)
+	}
+
+	for _, provider := range config.Providers {
+		if provider.Name == "" || provider.Command == "" {
+			return nil, fmt.Errorf("invalid credential provider configuration %s: every provider needs a name and a command", configPath)
+		}
+		if _, err := provider.cacheDuration(); err != nil {
+			return nil, fmt.Errorf("credential provider %s: %v", provider.Name, err)
+		}
+		for _, pattern := range provider.MatchRegistries {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("credential provider %s: invalid registry pattern %q", provider.Name, pattern)
+			}
+		}
+	}
+
+	return &Providers{
+		providers: config.Providers,
+		cache:     newCache(),
+	}, nil
+}
+
+// Registries maps the hostnames the providers name without wildcards to the
+// name of the first provider serving them.
+func (p *Providers) Registries() map[string]string {
+
+	registries := map[string]string{}
+	for _, provider := range p.providers {
+		for _, pattern := range provider.MatchRegistries {
+			if _, ok := registries[pattern]; ok || strings.ContainsAny(pattern, "*?[") {
+				continue
+			}
+			registries[pattern] = provider.Name
+		}
+	}
+
+	return registries
+}
+
+// Credential asks the first provider matching the registry for a credential.
+// It reports false when no provider serves the registry.
+func (p *Providers) Credential(registry string, repository string) (Response, bool, error) {
+
+	for _, provider := range p.providers {
+		if !provider.matches(registry) {
+			continue
+		}
+
+		key := provider.cacheKey() + "|" + registry + "|" + repository
+		if response, ok := p.cache.get(key); ok {
+			return response, true, nil
+		}
+
+		response, err := provider.exec(Request{Registry: registry, Repository: repository})
+		if err != nil {
+			return Response{}, true, fmt.Errorf("credential provider %s: %v", provider.Name, err)
+		}
+
+		if response.ExpiresAt == nil {
+			duration, _ := provider.cacheDuration()
+			expiresAt := time.Now().Add(duration)
+			response.ExpiresAt = &expiresAt
+		}
+		p.cache.put(key, response)
+
+		return response, true, nil
+	}
+
+	return Response{}, false, nil
+}
+
+func (provider ProviderConfig) matches(registry string) bool {
+	for _, pattern := range provider.MatchRegistries {
+		if ok, _ := path.Match(pattern, registry); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// cacheKey identifies the provider in the cache by its name and a hash of
+// how it is run, so that changing the command does not return the
+// credentials of the old one.
+func (provider ProviderConfig) cacheKey() string {
+
+	names := []string{}
+	for name := range provider.Env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	hash := sha256.New()
+	json.NewEncoder(hash).Encode(provider.Command)
+	json.NewEncoder(hash).Encode(provider.Args)
+	for _, name := range names {
+		json.NewEncoder(hash).Encode(name + "=" + provider.Env[name])
+	}
+
+	return fmt.Sprintf("%s@%x", provider.Name, hash.Sum(nil)[:8])
+}
+
+func (provider ProviderConfig) cacheDuration() (time.Duration, error) {
+	if provider.DefaultCacheDuration == "" {
+		return defaultCacheDuration, nil
+	}
+	return time.ParseDuration(provider.DefaultCacheDuration)
+}
+
+// exec runs the provider with the request on stdin and parses its stdout
+func (provider ProviderConfig) exec(request Request) (Response, error) {
+
+	input, err := json.Marshal(request)
+	if err != nil {
+		return Response{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), execTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, provider.Command, provider.Args...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stderr = os.Stderr
+	cmd.Env = os.Environ()
+	for name, value := range provider.Env {
+		cmd.Env = append(cmd.Env, name+"="+value)
+	}
+
+	output, err := cmd.Output()
+	if err != nil {
+		return Response{}, err
+	}
+
+	response := Response{}
+	if err := json.Unmarshal(output, &response); err != nil {
+		return Response{}, fmt.Errorf("invalid response: %v", err)
+	}
+	if response.Username == "" && response.Password == "" {
+		return Response{}, fmt.Errorf("response contains no credential")
+	}
+
+	return response, nil
+}
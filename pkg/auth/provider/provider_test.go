@@ -0,0 +1,287 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeProvider writes a provider script answering with the given username,
+// and counting its calls in a file next to it.
+func writeProvider(t *testing.T, dir string, username string) string {
+
+	if runtime.GOOS == "windows" {
+		t.Skip("the test provider is a shell script")
+	}
+
+	script := filepath.Join(dir, "provider-"+username)
+	content := fmt.Sprintf("#!/bin/sh\ncat > /dev/null\necho x >> \"$0.calls\"\necho '{\"username\": %q, \"password\": \"secret\"}'\n", username)
+	if err := ioutil.WriteFile(script, []byte(content), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	return script
+}
+
+func providerCalls(t *testing.T, script string) int {
+	content, err := ioutil.ReadFile(script + ".calls")
+	if os.IsNotExist(err) {
+		return 0
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Count(string(content), "x")
+}
+
+// loadProviders writes the configuration and loads it with the cache in a
+// temporary directory.
+func loadProviders(t *testing.T, dir string, config string) *Providers {
+
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(dir, "cache"))
+	t.Setenv("HOME", dir)
+
+	configPath := filepath.Join(dir, "providers.yaml")
+	if err := ioutil.WriteFile(configPath, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	providers, err := Load(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return providers
+}
+
+func TestLoadInvalid(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		config string
+	}{
+		{"missing command", "providers:\n- name: test\n  matchRegistries: [example.com]\n"},
+		{"invalid duration", "providers:\n- name: test\n  command: test\n  defaultCacheDuration: soon\n"},
+		{"invalid pattern", "providers:\n- name: test\n  command: test\n  matchRegistries: [\"[\"]\n"},
+		{"unknown field", "providers:\n- name: test\n  command: test\n  registries: [example.com]\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "providers.yaml")
+			if err := ioutil.WriteFile(configPath, []byte(test.config), 0600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := Load(configPath); err == nil {
+				t.Errorf("Load() succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestRegistries(t *testing.T) {
+
+	dir := t.TempDir()
+	providers := loadProviders(t, dir, "providers:\n"+
+		"- name: first\n  command: first\n  matchRegistries: [\"*.azurecr.io\", example.com]\n"+
+		"- name: second\n  command: second\n  matchRegistries: [example.com, docker.io]\n")
+
+	got := providers.Registries()
+	want := map[string]string{"example.com": "first", "docker.io": "second"}
+	if len(got) != len(want) {
+		t.Fatalf("Registries() = %v, want %v", got, want)
+	}
+	for hostname, name := range want {
+		if got[hostname] != name {
+			t.Errorf("Registries()[%q] = %q, want %q", hostname, got[hostname], name)
+		}
+	}
+}
+
+func TestCredential(t *testing.T) {
+
+	dir := t.TempDir()
+	first := writeProvider(t, dir, "first")
+	second := writeProvider(t, dir, "second")
+	providers := loadProviders(t, dir, fmt.Sprintf("providers:\n"+
+		"- name: first\n  command: %s\n  matchRegistries: [\"*.azurecr.io\"]\n"+
+		"- name: second\n  command: %s\n  matchRegistries: [\"*.azurecr.io\", example.com]\n", first, second))
+
+	tests := []struct {
+		registry string
+		want     string
+		ok       bool
+	}{
+		{"bupkis.azurecr.io", "first", true},
+		{"example.com", "second", true},
+		{"quay.io", "", false},
+	}
+
+	for _, test := range tests {
+		response, ok, err := providers.Credential(test.registry, "")
+		if err != nil {
+			t.Fatalf("Credential(%q) error: %v", test.registry, err)
+		}
+		if ok != test.ok || response.Username != test.want {
+			t.Errorf("Credential(%q) = %q, %v, want %q, %v", test.registry, response.Username, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestCredentialError(t *testing.T) {
+
+	if runtime.GOOS == "windows" {
+		t.Skip("the test provider is a shell script")
+	}
+
+	dir := t.TempDir()
+	script := filepath.Join(dir, "provider")
+	if err := ioutil.WriteFile(script, []byte("#!/bin/sh\ncat > /dev/null\necho '{}'\n"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	providers := loadProviders(t, dir, fmt.Sprintf("providers:\n- name: empty\n  command: %s\n  matchRegistries: [example.com]\n", script))
+
+	_, ok, err := providers.Credential("example.com", "")
+	if err == nil || !ok {
+		t.Errorf("Credential() = %v, %v, want an error from the matching provider", ok, err)
+	}
+}
+
+// TestCredentialCache checks that credentials are reused across loads until
+// the provider command changes.
+func TestCredentialCache(t *testing.T) {
+
+	dir := t.TempDir()
+	script := writeProvider(t, dir, "cached")
+	config := fmt.Sprintf("providers:\n- name: test\n  command: %s\n  matchRegistries: [example.com]\n", script)
+
+	for i := 0; i < 2; i++ {
+		providers := loadProviders(t, dir, config)
+		if _, _, err := providers.Credential("example.com", "repo"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls := providerCalls(t, script); calls != 1 {
+		t.Errorf("provider ran %d times, want 1", calls)
+	}
+
+	// another repository gets its own credential
+	providers := loadProviders(t, dir, config)
+	if _, _, err := providers.Credential("example.com", "other"); err != nil {
+		t.Fatal(err)
+	}
+	if calls := providerCalls(t, script); calls != 2 {
+		t.Errorf("provider ran %d times, want 2", calls)
+	}
+
+	// the same name with other arguments is another provider
+	providers = loadProviders(t, dir, config+"  args: [--scope, pull]\n")
+	if _, _, err := providers.Credential("example.com", "repo"); err != nil {
+		t.Fatal(err)
+	}
+	if calls := providerCalls(t, script); calls != 3 {
+		t.Errorf("provider ran %d times, want 3", calls)
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+
+	base := ProviderConfig{Name: "test", Command: "provider", Args: []string{"a"}, Env: map[string]string{"A": "1"}}
+
+	tests := []struct {
+		name     string
+		provider ProviderConfig
+	}{
+		{"command", ProviderConfig{Name: "test", Command: "other", Args: []string{"a"}, Env: map[string]string{"A": "1"}}},
+		{"args", ProviderConfig{Name: "test", Command: "provider", Args: []string{"b"}, Env: map[string]string{"A": "1"}}},
+		{"joined args", ProviderConfig{Name: "test", Command: "provider a", Env: map[string]string{"A": "1"}}},
+		{"env", ProviderConfig{Name: "test", Command: "provider", Args: []string{"a"}, Env: map[string]string{"A": "2"}}},
+	}
+
+	for _, test := range tests {
+		if base.cacheKey() == test.provider.cacheKey() {
+			t.Errorf("changing the %s keeps the cache key %s", test.name, base.cacheKey())
+		}
+	}
+
+	same := ProviderConfig{Name: "test", Command: "provider", Args: []string{"a"}, Env: map[string]string{"A": "1"}, MatchRegistries: []string{"example.com"}}
+	if base.cacheKey() != same.cacheKey() {
+		t.Errorf("cacheKey() = %s, want %s", same.cacheKey(), base.cacheKey())
+	}
+}
+
+func TestCachePermissions(t *testing.T) {
+
+	if runtime.GOOS == "windows" {
+		t.Skip("windows has no permission bits")
+	}
+
+	dir := t.TempDir()
+	cacheDir := filepath.Join(dir, "cache", "bupkis")
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(cacheDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	script := writeProvider(t, dir, "test")
+	providers := loadProviders(t, dir, fmt.Sprintf("providers:\n- name: test\n  command: %s\n  matchRegistries: [example.com]\n", script))
+	if _, _, err := providers.Credential("example.com", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(cacheDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0700 {
+		t.Errorf("cache directory mode = %o, want 700", mode)
+	}
+
+	info, err = os.Stat(filepath.Join(cacheDir, "credential-providers.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0600 {
+		t.Errorf("cache file mode = %o, want 600", mode)
+	}
+}
+
+func TestCacheDropsUnknownExpiry(t *testing.T) {
+
+	dir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", dir)
+	t.Setenv("HOME", dir)
+
+	later := time.Now().Add(time.Hour)
+	earlier := time.Now().Add(-time.Hour)
+	content, err := json.Marshal(map[string]Response{
+		"valid":   {Username: "valid", ExpiresAt: &later},
+		"expired": {Username: "expired", ExpiresAt: &earlier},
+		"unknown": {Username: "unknown"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "bupkis"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "bupkis", "credential-providers.json"), content, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	c := newCache()
+	if len(c.entries) != 1 {
+		t.Errorf("cache entries = %v, want only the valid one", c.entries)
+	}
+	if _, ok := c.get("valid"); !ok {
+		t.Errorf("get(%q) found nothing", "valid")
+	}
+}
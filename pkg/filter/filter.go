@@ -0,0 +1,94 @@
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Matcher decides whether a repository or tag name is selected
+type Matcher func(name string) bool
+
+// Tags returns the tags accepted by match, keeping their order
+func Tags(tags []string, match Matcher) []string {
+	returnTags := []string{}
+	for _, tag := range tags {
+		if match(tag) {
+			returnTags = append(returnTags, tag)
+		}
+	}
+	return returnTags
+}
+
+// All returns a matcher accepting names accepted by every one of matchers
+func All(matchers ...Matcher) Matcher {
+	return func(name string) bool {
+		for _, match := range matchers {
+			if !match(name) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Substring returns a matcher for names containing s
+func Substring(s string) Matcher {
+	return func(name string) bool {
+		return strings.Contains(name, s)
+	}
+}
+
+// Regex returns a matcher for names matching the regular expression
+func Regex(expr string) (Matcher, error) {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex %q: %v", expr, err)
+	}
+
+	return re.MatchString, nil
+}
+
+// Glob returns a matcher for names matching the whole glob pattern. Unlike
+// path.Match, * also matches slashes, so team-a/* selects nested repositories.
+func Glob(pattern string) (Matcher, error) {
+	expr := strings.Builder{}
+	expr.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			expr.WriteString(".*")
+		case '?':
+			expr.WriteString(".")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	expr.WriteString("$")
+
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid glob %q: %v", pattern, err)
+	}
+
+	return re.MatchString, nil
+}
+
+// Not returns a matcher accepting the names match rejects
+func Not(match Matcher) Matcher {
+	return func(name string) bool {
+		return !match(name)
+	}
+}
+
+// Any returns a matcher accepting names accepted by at least one of matchers
+func Any(matchers ...Matcher) Matcher {
+	return func(name string) bool {
+		for _, match := range matchers {
+			if match(name) {
+				return true
+			}
+		}
+		return false
+	}
+}
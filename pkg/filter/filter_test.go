@@ -0,0 +1,114 @@
+package filter
+
+import (
+	"reflect"
+	"testing"
+)
+
+var testTags = []string{"latest", "1.9.0", "1.20.0", "1.20.1-rc.1", "v1.21", "2.0.0", "20200101", "dev-build"}
+
+func TestRegex(t *testing.T) {
+
+	match, err := Regex(`^1\.20`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := Tags(testTags, match)
+	if want := []string{"1.20.0", "1.20.1-rc.1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Tags() = %v, want %v", got, want)
+	}
+
+	if _, err := Regex("("); err == nil {
+		t.Error("Regex accepted an invalid expression")
+	}
+}
+
+func TestSemverConstraint(t *testing.T) {
+
+	match, err := SemverConstraint(">= 1.20, < 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// pre-releases and tags that are not versions never match
+	got := Tags(testTags, match)
+	if want := []string{"1.20.0", "v1.21"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Tags() = %v, want %v", got, want)
+	}
+
+	if _, err := SemverConstraint("not a constraint"); err == nil {
+		t.Error("SemverConstraint accepted an invalid constraint")
+	}
+}
+
+func TestAll(t *testing.T) {
+
+	semver, err := SemverConstraint(">= 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	regex, err := Regex(`\.0$`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := Tags(testTags, All(semver, regex))
+	if want := []string{"1.9.0", "1.20.0", "2.0.0"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Tags() = %v, want %v", got, want)
+	}
+
+	if got := Tags(testTags, All()); !reflect.DeepEqual(got, testTags) {
+		t.Errorf("All() without matchers selected %v, want every tag", got)
+	}
+}
+
+func TestGlob(t *testing.T) {
+
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"team-a/*", "team-a/web", true},
+		{"team-a/*", "team-a/web/api", true},
+		{"team-a/*", "team-b/web", false},
+		{"web?", "web1", true},
+		{"web?", "web12", false},
+		{"v1.*", "v1.2", true},
+		{"v1.*", "v1-2", false},
+		{"*.io", "registry.io", true},
+	}
+
+	for _, test := range tests {
+		match, err := Glob(test.pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := match(test.name); got != test.want {
+			t.Errorf("Glob(%q) matches %q = %v, want %v", test.pattern, test.name, got, test.want)
+		}
+	}
+}
+
+func TestSubstring(t *testing.T) {
+
+	got := Tags(testTags, Substring("20"))
+	if want := []string{"1.20.0", "1.20.1-rc.1", "20200101"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Tags() = %v, want %v", got, want)
+	}
+}
+
+func TestNotAny(t *testing.T) {
+
+	match := Any(Substring("rc"), Substring("dev"))
+	if got, want := Tags(testTags, match), []string{"1.20.1-rc.1", "dev-build"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Any() selected %v, want %v", got, want)
+	}
+	if got := Tags(testTags, Any()); len(got) != 0 {
+		t.Errorf("Any() without matchers selected %v, want nothing", got)
+	}
+	if got, want := Tags(testTags, Not(match)), []string{"latest", "1.9.0", "1.20.0", "v1.21", "2.0.0", "20200101"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Not() selected %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,81 @@
+package filter
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zawachte-msft/bupkis/pkg/registry"
+)
+
+// Images returns the images accepted by keep, keeping their order
+func Images(images []registry.ImageData, keep func(registry.ImageData) bool) []registry.ImageData {
+	returnImages := []registry.ImageData{}
+	for _, image := range images {
+		if keep(image) {
+			returnImages = append(returnImages, image)
+		}
+	}
+	return returnImages
+}
+
+// CreatedAfter accepts images created after t
+func CreatedAfter(t time.Time) func(registry.ImageData) bool {
+	return func(image registry.ImageData) bool {
+		return image.Created.After(t)
+	}
+}
+
+// CreatedBefore accepts images created before t
+func CreatedBefore(t time.Time) func(registry.ImageData) bool {
+	return func(image registry.ImageData) bool {
+		return image.Created.Before(t)
+	}
+}
+
+// Newest keeps the n most recently created images of each repository, keeping their order
+func Newest(images []registry.ImageData, n int) []registry.ImageData {
+
+	byRepo := map[string][]registry.ImageData{}
+	for _, image := range images {
+		key := image.Hostname + "/" + image.Name
+		byRepo[key] = append(byRepo[key], image)
+	}
+
+	keep := map[string]bool{}
+	for _, repoImages := range byRepo {
+		sort.SliceStable(repoImages, func(i, j int) bool { return repoImages[i].Created.After(repoImages[j].Created) })
+		for i := 0; i < n && i < len(repoImages); i++ {
+			keep[repoImages[i].Hostname+"/"+repoImages[i].Name+":"+repoImages[i].Tag] = true
+		}
+	}
+
+	return Images(images, func(image registry.ImageData) bool {
+		return keep[image.Hostname+"/"+image.Name+":"+image.Tag]
+	})
+}
+
+// ParseTime reads a point in time given either as an age relative to now,
+// such as 7d, 2w or 36h, or as a date such as 2026-01-01 or an RFC 3339 timestamp.
+func ParseTime(value string, now time.Time) (time.Time, error) {
+
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if count, err := strconv.Atoi(strings.TrimSuffix(value, suffix)); err == nil && strings.HasSuffix(value, suffix) {
+			return now.Add(-time.Duration(count) * unit), nil
+		}
+	}
+
+	if duration, err := time.ParseDuration(value); err == nil {
+		return now.Add(-duration), nil
+	}
+
+	for _, layout := range []string{"2006-01-02", time.RFC3339} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid time %q, expected an age like 7d or a date like 2006-01-02", value)
+}
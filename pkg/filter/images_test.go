@@ -0,0 +1,82 @@
+package filter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zawachte-msft/bupkis/pkg/registry"
+)
+
+var testNow = time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func imageTags(images []registry.ImageData) []string {
+	tags := []string{}
+	for _, image := range images {
+		tags = append(tags, image.Name+":"+image.Tag)
+	}
+	return tags
+}
+
+func TestParseTime(t *testing.T) {
+
+	tests := map[string]time.Time{
+		"7d":                   testNow.Add(-7 * 24 * time.Hour),
+		"2w":                   testNow.Add(-14 * 24 * time.Hour),
+		"36h":                  testNow.Add(-36 * time.Hour),
+		"2026-01-01":           time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		"2026-01-01T10:00:00Z": time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC),
+	}
+
+	for value, want := range tests {
+		got, err := ParseTime(value, testNow)
+		if err != nil || !got.Equal(want) {
+			t.Errorf("ParseTime(%q) = %v, %v, want %v", value, got, err, want)
+		}
+	}
+
+	for _, value := range []string{"", "7y", "yesterday", "2026-13-01"} {
+		if _, err := ParseTime(value, testNow); err == nil {
+			t.Errorf("ParseTime(%q) accepted an invalid time", value)
+		}
+	}
+}
+
+func TestCreatedFilters(t *testing.T) {
+
+	images := []registry.ImageData{
+		{Name: "app", Tag: "old", Created: testNow.Add(-30 * 24 * time.Hour)},
+		{Name: "app", Tag: "new", Created: testNow.Add(-time.Hour)},
+		{Name: "app", Tag: "unknown"},
+	}
+	weekAgo := testNow.Add(-7 * 24 * time.Hour)
+
+	if got := imageTags(Images(images, CreatedAfter(weekAgo))); len(got) != 1 || got[0] != "app:new" {
+		t.Errorf("CreatedAfter() kept %v, want app:new", got)
+	}
+	// an image without a creation time counts as created before anything
+	if got := imageTags(Images(images, CreatedBefore(weekAgo))); len(got) != 2 || got[0] != "app:old" || got[1] != "app:unknown" {
+		t.Errorf("CreatedBefore() kept %v, want app:old and app:unknown", got)
+	}
+}
+
+func TestNewest(t *testing.T) {
+
+	images := []registry.ImageData{
+		{Name: "app", Tag: "1", Created: testNow.Add(-3 * time.Hour)},
+		{Name: "web", Tag: "1", Created: testNow.Add(-3 * time.Hour)},
+		{Name: "app", Tag: "3", Created: testNow.Add(-1 * time.Hour)},
+		{Name: "app", Tag: "2", Created: testNow.Add(-2 * time.Hour)},
+	}
+
+	got := imageTags(Newest(images, 2))
+	want := []string{"web:1", "app:3", "app:2"}
+	if len(got) != len(want) {
+		t.Fatalf("Newest() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Newest() = %v, want %v", got, want)
+			break
+		}
+	}
+}
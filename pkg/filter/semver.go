@@ -0,0 +1,114 @@
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// semverTagRegexp accepts MAJOR.MINOR with an optional PATCH, v prefix,
+// pre-release and build metadata. Plain numbers such as dates are left out.
+var semverTagRegexp = regexp.MustCompile(`^v?\d+\.\d+(\.\d+)?(-[0-9A-Za-z.-]+)?(\+[0-9A-Za-z.-]+)?$`)
+
+// ParseSemver reads a tag as a semantic version, reporting false for tags that are not one
+func ParseSemver(tag string) (*semver.Version, bool) {
+	if !semverTagRegexp.MatchString(tag) {
+		return nil, false
+	}
+
+	version, err := semver.NewVersion(tag)
+	if err != nil {
+		return nil, false
+	}
+
+	return version, true
+}
+
+// SemverConstraint returns a matcher for tags that are semantic versions
+// satisfying the constraint, such as ">= 1.20, < 2". Other tags never match.
+func SemverConstraint(constraint string) (Matcher, error) {
+	return semverMatcher(constraint, false)
+}
+
+// Latest returns the highest semantic version tag satisfying the constraint,
+// or every version when the constraint is empty. Pre-releases are only
+// considered with includePrerelease, and are then matched by their release
+// version, so ^1.4 accepts 1.5.0-rc.1.
+func Latest(tags []string, constraint string, includePrerelease bool) (string, bool, error) {
+
+	match, err := semverMatcher(constraint, includePrerelease)
+	if err != nil {
+		return "", false, err
+	}
+
+	latestTag := ""
+	var latest *semver.Version
+	for _, tag := range tags {
+		version, ok := ParseSemver(tag)
+		if !ok || !match(tag) {
+			continue
+		}
+
+		if latest == nil || version.GreaterThan(latest) {
+			latest = version
+			latestTag = tag
+		}
+	}
+
+	return latestTag, latest != nil, nil
+}
+
+// VersionLine returns a matcher for the tags on the same release line as tag.
+// For a semantic version these are the versions with the same major, minor
+// and pre-release, so 22.04 selects 22.04.1 and 1.25-alpine selects
+// 1.25.3-alpine. Other tags select the tags they prefix, so jammy selects
+// jammy-20240110.
+func VersionLine(tag string) Matcher {
+
+	line, ok := ParseSemver(tag)
+	if !ok {
+		return func(name string) bool {
+			return strings.HasPrefix(name, tag)
+		}
+	}
+
+	return func(name string) bool {
+		version, ok := ParseSemver(name)
+		return ok && version.Major() == line.Major() && version.Minor() == line.Minor() && version.Prerelease() == line.Prerelease()
+	}
+}
+
+func semverMatcher(constraint string, includePrerelease bool) (Matcher, error) {
+
+	var constraints *semver.Constraints
+	if constraint != "" {
+		var err error
+		constraints, err = semver.NewConstraint(constraint)
+		if err != nil {
+			return nil, fmt.Errorf("invalid semver constraint %q: %v", constraint, err)
+		}
+	}
+
+	return func(tag string) bool {
+		version, ok := ParseSemver(tag)
+		if !ok {
+			return false
+		}
+
+		if version.Prerelease() != "" {
+			if !includePrerelease {
+				return constraints != nil && constraints.Check(version)
+			}
+
+			release, err := version.SetPrerelease("")
+			if err != nil {
+				return false
+			}
+			version = &release
+		}
+
+		return constraints == nil || constraints.Check(version)
+	}, nil
+}
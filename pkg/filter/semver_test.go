@@ -0,0 +1,75 @@
+package filter
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestVersionLine(t *testing.T) {
+
+	tags := []string{"latest", "22.04", "22.04.1", "22.10", "jammy", "jammy-20240110", "noble", "1.25-alpine", "1.25.3-alpine", "1.25.3", "1.26-alpine"}
+	tests := map[string][]string{
+		"22.04":       {"22.04", "22.04.1"},
+		"jammy":       {"jammy", "jammy-20240110"},
+		"1.25-alpine": {"1.25-alpine", "1.25.3-alpine"},
+		"1.25.3":      {"1.25.3"},
+		"latest":      {"latest"},
+	}
+
+	for tag, want := range tests {
+		if got := Tags(tags, VersionLine(tag)); !reflect.DeepEqual(got, want) {
+			t.Errorf("VersionLine(%q) selected %v, want %v", tag, got, want)
+		}
+	}
+}
+
+func TestLatest(t *testing.T) {
+
+	tags := []string{"latest", "1.4.2", "v1.5.0", "1.5.1-rc.1", "1.10.0", "2.0.0-beta.1", "20240101", "1.9"}
+	tests := []struct {
+		constraint        string
+		includePrerelease bool
+		want              string
+		found             bool
+	}{
+		// 1.10.0 is higher than 1.9, and dates are not versions
+		{"", false, "1.10.0", true},
+		{"^1.4, < 1.6", false, "v1.5.0", true},
+		{"^1.4, < 1.6", true, "1.5.1-rc.1", true},
+		{"", true, "2.0.0-beta.1", true},
+		{">= 3", false, "", false},
+	}
+
+	for _, test := range tests {
+		got, found, err := Latest(tags, test.constraint, test.includePrerelease)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != test.want || found != test.found {
+			t.Errorf("Latest(%q, %v) = %q, %v, want %q, %v", test.constraint, test.includePrerelease, got, found, test.want, test.found)
+		}
+	}
+
+	if _, _, err := Latest(tags, "not a constraint", false); err == nil {
+		t.Error("Latest accepted an invalid constraint")
+	}
+}
+
+func TestParseSemver(t *testing.T) {
+
+	for tag, want := range map[string]bool{
+		"1.2":         true,
+		"v1.2.3":      true,
+		"1.2.3-rc.1":  true,
+		"1.2.3+build": true,
+		"1":           false,
+		"20240101":    false,
+		"latest":      false,
+		"1.2.3.4":     false,
+		"release-1.2": false,
+	} {
+		if _, got := ParseSemver(tag); got != want {
+			t.Errorf("ParseSemver(%q) = %v, want %v", tag, got, want)
+		}
+	}
+}
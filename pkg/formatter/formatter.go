@@ -7,8 +7,12 @@ import (
 )
 
 func PrintOutput(data [][]string) {
+	PrintTable([]string{"Name", "Tag", "Created"}, data)
+}
+
+func PrintTable(header []string, data [][]string) {
 	table := tablewriter.NewWriter(os.Stdout)
-	table.SetHeader([]string{"Name", "Tag", "Created"})
+	table.SetHeader(header)
 	table.SetAutoWrapText(false)
 	table.SetAutoFormatHeaders(true)
 	table.SetHeaderAlignment(tablewriter.ALIGN_LEFT)
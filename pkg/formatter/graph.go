@@ -0,0 +1,64 @@
+package formatter
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Graph is a directed graph to render as a diagram
+type Graph struct {
+	Nodes []GraphNode
+	Edges []GraphEdge
+}
+
+// GraphNode is a node of a Graph. Lines of the label are separated by newlines.
+type GraphNode struct {
+	ID    string
+	Label string
+}
+
+// GraphEdge connects two nodes of a Graph by their ID
+type GraphEdge struct {
+	From  string
+	To    string
+	Label string
+}
+
+// WriteDOT renders the graph in the Graphviz DOT language
+func WriteDOT(w io.Writer, graph Graph) {
+	fmt.Fprintln(w, "digraph {")
+	fmt.Fprintln(w, "  rankdir=LR;")
+	fmt.Fprintln(w, "  node [shape=box];")
+	for _, node := range graph.Nodes {
+		fmt.Fprintf(w, "  %s [label=%s];\n", node.ID, dotQuote(node.Label))
+	}
+	for _, edge := range graph.Edges {
+		fmt.Fprintf(w, "  %s -> %s [label=%s];\n", edge.From, edge.To, dotQuote(edge.Label))
+	}
+	fmt.Fprintln(w, "}")
+}
+
+// WriteMermaid renders the graph as a Mermaid flowchart
+func WriteMermaid(w io.Writer, graph Graph) {
+	fmt.Fprintln(w, "graph LR")
+	for _, node := range graph.Nodes {
+		fmt.Fprintf(w, "  %s[%s]\n", node.ID, mermaidQuote(node.Label))
+	}
+	for _, edge := range graph.Edges {
+		fmt.Fprintf(w, "  %s -->|%s| %s\n", edge.From, mermaidQuote(edge.Label), edge.To)
+	}
+}
+
+func dotQuote(label string) string {
+	label = strings.ReplaceAll(label, `\`, `\\`)
+	label = strings.ReplaceAll(label, `"`, `\"`)
+	label = strings.ReplaceAll(label, "\n", `\n`)
+	return `"` + label + `"`
+}
+
+func mermaidQuote(label string) string {
+	label = strings.ReplaceAll(label, `"`, "#quot;")
+	label = strings.ReplaceAll(label, "\n", "<br/>")
+	return `"` + label + `"`
+}
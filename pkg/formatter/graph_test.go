@@ -0,0 +1,49 @@
+package formatter
+
+import (
+	"bytes"
+	"testing"
+)
+
+var testGraph = Graph{
+	Nodes: []GraphNode{
+		{ID: "n0", Label: "base/os:1\n100B, 2 days ago"},
+		{ID: "n1", Label: `app:"quoted"`},
+	},
+	Edges: []GraphEdge{
+		{From: "n0", To: "n1", Label: "1 shared layer"},
+	},
+}
+
+func TestWriteDOT(t *testing.T) {
+
+	buffer := &bytes.Buffer{}
+	WriteDOT(buffer, testGraph)
+
+	want := `digraph {
+  rankdir=LR;
+  node [shape=box];
+  n0 [label="base/os:1\n100B, 2 days ago"];
+  n1 [label="app:\"quoted\""];
+  n0 -> n1 [label="1 shared layer"];
+}
+`
+	if got := buffer.String(); got != want {
+		t.Errorf("WriteDOT() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWriteMermaid(t *testing.T) {
+
+	buffer := &bytes.Buffer{}
+	WriteMermaid(buffer, testGraph)
+
+	want := `graph LR
+  n0["base/os:1<br/>100B, 2 days ago"]
+  n1["app:#quot;quoted#quot;"]
+  n0 -->|"1 shared layer"| n1
+`
+	if got := buffer.String(); got != want {
+		t.Errorf("WriteMermaid() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,35 @@
+package formatter
+
+// TreeItem is a row of a tree printed by PrintTree
+type TreeItem struct {
+	Name     string
+	Columns  []string
+	Children []TreeItem
+}
+
+// PrintTree prints the items as a table whose first column draws the
+// hierarchy, e.g. "└── service".
+func PrintTree(header []string, roots []TreeItem) {
+
+	data := [][]string{}
+	for _, root := range roots {
+		data = appendTreeRows(data, root, "", "")
+	}
+
+	PrintTable(header, data)
+}
+
+func appendTreeRows(data [][]string, item TreeItem, prefix string, childPrefix string) [][]string {
+
+	data = append(data, append([]string{prefix + item.Name}, item.Columns...))
+
+	for i, child := range item.Children {
+		if i == len(item.Children)-1 {
+			data = appendTreeRows(data, child, childPrefix+"└── ", childPrefix+"    ")
+		} else {
+			data = appendTreeRows(data, child, childPrefix+"├── ", childPrefix+"│   ")
+		}
+	}
+
+	return data
+}
@@ -0,0 +1,28 @@
+package formatter
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAppendTreeRows(t *testing.T) {
+
+	root := TreeItem{Name: "registry.example.com", Columns: []string{"3"}, Children: []TreeItem{
+		{Name: "team-a", Columns: []string{"2"}, Children: []TreeItem{
+			{Name: "api", Columns: []string{"1"}},
+			{Name: "web", Columns: []string{"1"}},
+		}},
+		{Name: "tool", Columns: []string{"1"}},
+	}}
+
+	want := [][]string{
+		{"registry.example.com", "3"},
+		{"├── team-a", "2"},
+		{"│   ├── api", "1"},
+		{"│   └── web", "1"},
+		{"└── tool", "1"},
+	}
+	if got := appendTreeRows(nil, root, "", ""); !reflect.DeepEqual(got, want) {
+		t.Errorf("appendTreeRows() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,246 @@
+package layout
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/docker/distribution"
+	"github.com/docker/distribution/manifest"
+	"github.com/docker/distribution/manifest/schema2"
+	digest "github.com/opencontainers/go-digest"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// dockerArchiveEntry is one image in the manifest.json written by docker save
+type dockerArchiveEntry struct {
+	Config   string
+	RepoTags []string
+	Layers   []string
+}
+
+// dockerArchive locates the files of a docker save tarball, so that each one
+// is read directly instead of scanning the tarball again.
+type dockerArchive struct {
+	path  string
+	file  *os.File
+	files map[string]*io.SectionReader
+	// links are the symlinked files, which docker save uses for layers shared between images
+	links map[string]string
+}
+
+// ImportDockerArchive converts the images in a docker save tarball into
+// schema2 manifests stored in the layout. Layers are gzip compressed as
+// schema2 requires, and each repo tag of an image becomes a ref name in the
+// index, like alpine:3.18.
+func ImportDockerArchive(archivePath string, l *Layout) error {
+
+	archive, err := openDockerArchive(archivePath)
+	if err != nil {
+		return err
+	}
+	defer archive.file.Close()
+
+	entries := []dockerArchiveEntry{}
+	err = archive.read("manifest.json", func(content io.Reader) error {
+		return json.NewDecoder(content).Decode(&entries)
+	})
+	if err != nil {
+		return err
+	}
+
+	layers := map[string]distribution.Descriptor{}
+
+	for _, entry := range entries {
+
+		var config []byte
+		err := archive.read(entry.Config, func(content io.Reader) (err error) {
+			config, err = ioutil.ReadAll(content)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		configDesc := distribution.Descriptor{
+			MediaType: schema2.MediaTypeImageConfig,
+			Digest:    digest.FromBytes(config),
+			Size:      int64(len(config)),
+		}
+		if err := l.WriteBlob(v1.Descriptor{Digest: configDesc.Digest, Size: configDesc.Size}, bytes.NewReader(config)); err != nil {
+			return err
+		}
+
+		image := schema2.Manifest{
+			Versioned: manifest.Versioned{
+				SchemaVersion: 2,
+				MediaType:     schema2.MediaTypeManifest,
+			},
+			Config: configDesc,
+		}
+
+		for _, layerPath := range entry.Layers {
+			layerDesc, ok := layers[layerPath]
+			if !ok {
+				err := archive.read(layerPath, func(content io.Reader) (err error) {
+					layerDesc, err = l.writeCompressedLayer(content)
+					return err
+				})
+				if err != nil {
+					return err
+				}
+				layers[layerPath] = layerDesc
+			}
+
+			image.Layers = append(image.Layers, layerDesc)
+		}
+
+		content, err := json.Marshal(image)
+		if err != nil {
+			return err
+		}
+
+		desc := v1.Descriptor{
+			MediaType: schema2.MediaTypeManifest,
+			Digest:    digest.FromBytes(content),
+			Size:      int64(len(content)),
+		}
+		if err := l.WriteBlob(desc, bytes.NewReader(content)); err != nil {
+			return err
+		}
+
+		if len(entry.RepoTags) == 0 {
+			if err := l.AddToIndex(desc, ""); err != nil {
+				return err
+			}
+		}
+
+		// the repository is kept, as images of several repositories may share a tag
+		for _, repoTag := range entry.RepoTags {
+			if err := l.AddToIndex(desc, repoTag); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// writeCompressedLayer stores a layer tar, gzip compressing it unless docker already did.
+func (l *Layout) writeCompressedLayer(content io.Reader) (distribution.Descriptor, error) {
+
+	file, err := ioutil.TempFile("", "bupkis-layer")
+	if err != nil {
+		return distribution.Descriptor{}, err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	buffered := bufio.NewReader(content)
+	magic, _ := buffered.Peek(2)
+
+	digester := digest.Canonical.Digester()
+	output := io.MultiWriter(file, digester.Hash())
+
+	if bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		if _, err := io.Copy(output, buffered); err != nil {
+			return distribution.Descriptor{}, err
+		}
+	} else {
+		gzipWriter := gzip.NewWriter(output)
+		if _, err := io.Copy(gzipWriter, buffered); err != nil {
+			return distribution.Descriptor{}, err
+		}
+		if err := gzipWriter.Close(); err != nil {
+			return distribution.Descriptor{}, err
+		}
+	}
+
+	size, err := file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return distribution.Descriptor{}, err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return distribution.Descriptor{}, err
+	}
+
+	desc := distribution.Descriptor{
+		MediaType: schema2.MediaTypeLayer,
+		Digest:    digester.Digest(),
+		Size:      size,
+	}
+
+	return desc, l.WriteBlob(v1.Descriptor{Digest: desc.Digest, Size: desc.Size}, file)
+}
+
+// openDockerArchive reads the headers of the tarball once. The content of
+// the files is skipped by seeking.
+func openDockerArchive(archivePath string) (*dockerArchive, error) {
+
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return nil, err
+	}
+
+	archive := &dockerArchive{
+		path:  archivePath,
+		file:  file,
+		files: map[string]*io.SectionReader{},
+		links: map[string]string{},
+	}
+
+	tarReader := tar.NewReader(file)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return archive, nil
+		}
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+
+		name := path.Clean(header.Name)
+		switch header.Typeflag {
+		case tar.TypeReg, tar.TypeRegA:
+			// the content of the file starts right after its header
+			offset, err := file.Seek(0, io.SeekCurrent)
+			if err != nil {
+				file.Close()
+				return nil, err
+			}
+			archive.files[name] = io.NewSectionReader(file, offset, header.Size)
+		case tar.TypeSymlink:
+			archive.links[name] = path.Join(path.Dir(name), header.Linkname)
+		case tar.TypeLink:
+			archive.links[name] = path.Clean(header.Linkname)
+		}
+	}
+}
+
+// read calls read with the content of the named file in the tarball.
+func (a *dockerArchive) read(name string, read func(io.Reader) error) error {
+
+	name = path.Clean(name)
+	for i := 0; i < 16; i++ {
+		target, ok := a.links[name]
+		if !ok {
+			break
+		}
+		name = target
+	}
+
+	section, ok := a.files[name]
+	if !ok {
+		return fmt.Errorf("%s not found in %s", name, a.path)
+	}
+
+	return read(io.NewSectionReader(section, 0, section.Size()))
+}
@@ -0,0 +1,146 @@
+package layout
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/distribution/manifest/schema2"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+type archiveFile struct {
+	name    string
+	content string
+	link    string
+}
+
+func writeArchive(t *testing.T, files []archiveFile) string {
+
+	buffer := &bytes.Buffer{}
+	tarWriter := tar.NewWriter(buffer)
+	for _, file := range files {
+		header := &tar.Header{Name: file.name, Mode: 0644, Size: int64(len(file.content)), Typeflag: tar.TypeReg}
+		if file.link != "" {
+			header = &tar.Header{Name: file.name, Mode: 0777, Linkname: file.link, Typeflag: tar.TypeSymlink}
+		}
+		if err := tarWriter.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tarWriter.Write([]byte(file.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tarWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	archivePath := filepath.Join(t.TempDir(), "images.tar")
+	if err := ioutil.WriteFile(archivePath, buffer.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return archivePath
+}
+
+func TestImportDockerArchive(t *testing.T) {
+
+	// alpine and nginx share a tag and a layer, which docker save links
+	manifests := []dockerArchiveEntry{
+		{Config: "alpine.json", RepoTags: []string{"alpine:3.18"}, Layers: []string{"base/layer.tar"}},
+		{Config: "nginx.json", RepoTags: []string{"nginx:3.18", "nginx:latest"}, Layers: []string{"shared/layer.tar", "nginx/layer.tar"}},
+	}
+	manifestJSON, err := json.Marshal(manifests)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	archivePath := writeArchive(t, []archiveFile{
+		{name: "base/layer.tar", content: "base layer"},
+		{name: "shared/layer.tar", link: "../base/layer.tar"},
+		{name: "nginx/layer.tar", content: "nginx layer"},
+		{name: "alpine.json", content: `{"architecture": "amd64", "os": "linux"}`},
+		{name: "nginx.json", content: `{"architecture": "arm64", "os": "linux"}`},
+		{name: "manifest.json", content: string(manifestJSON)},
+	})
+
+	l, err := New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ImportDockerArchive(archivePath, l); err != nil {
+		t.Fatal(err)
+	}
+
+	index, err := l.Index()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	images := map[string]schema2.Manifest{}
+	for _, desc := range index.Manifests {
+		content, err := l.ReadBlob(desc.Digest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		image := schema2.Manifest{}
+		if err := json.Unmarshal(content, &image); err != nil {
+			t.Fatal(err)
+		}
+		images[desc.Annotations[v1.AnnotationRefName]] = image
+	}
+
+	if len(images) != 3 {
+		t.Fatalf("index names %d images, want alpine:3.18, nginx:3.18 and nginx:latest: %+v", len(images), index.Manifests)
+	}
+	alpine, nginx := images["alpine:3.18"], images["nginx:3.18"]
+	if alpine.Config.Digest == nginx.Config.Digest {
+		t.Errorf("alpine:3.18 and nginx:3.18 are the same image")
+	}
+	if images["nginx:latest"].Config.Digest != nginx.Config.Digest {
+		t.Errorf("nginx:latest and nginx:3.18 are different images")
+	}
+
+	if len(alpine.Layers) != 1 || len(nginx.Layers) != 2 || nginx.Layers[0].Digest != alpine.Layers[0].Digest {
+		t.Fatalf("layers of alpine %+v and nginx %+v, want nginx to start with the layer of alpine", alpine.Layers, nginx.Layers)
+	}
+
+	// layers are stored gzip compressed
+	for i, want := range []string{"base layer", "nginx layer"} {
+		blob, err := l.OpenBlob(nginx.Layers[i].Digest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gzipReader, err := gzip.NewReader(blob)
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := ioutil.ReadAll(gzipReader)
+		blob.Close()
+		if err != nil || string(content) != want {
+			t.Errorf("layer %d = %q, %v, want %q", i, content, err, want)
+		}
+	}
+}
+
+func TestImportDockerArchiveMissingFile(t *testing.T) {
+
+	archivePath := writeArchive(t, []archiveFile{
+		{name: "manifest.json", content: `[{"Config": "missing.json", "RepoTags": ["app:1"], "Layers": []}]`},
+	})
+
+	l, err := New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ImportDockerArchive(archivePath, l); err == nil {
+		t.Error("ImportDockerArchive accepted an archive without the config of its image")
+	}
+	if _, err := os.Stat(archivePath); err != nil {
+		t.Fatal(err)
+	}
+}
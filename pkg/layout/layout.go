@@ -0,0 +1,202 @@
+package layout
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	digest "github.com/opencontainers/go-digest"
+	specs "github.com/opencontainers/image-spec/specs-go"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// Layout is an OCI image layout directory
+type Layout struct {
+	root string
+}
+
+// New opens the image layout at root, creating it if it does not exist yet.
+func New(root string) (*Layout, error) {
+
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, err
+	}
+
+	layoutPath := filepath.Join(root, v1.ImageLayoutFile)
+	if _, err := os.Stat(layoutPath); os.IsNotExist(err) {
+		content, err := json.Marshal(v1.ImageLayout{Version: v1.ImageLayoutVersion})
+		if err != nil {
+			return nil, err
+		}
+
+		if err := ioutil.WriteFile(layoutPath, content, 0644); err != nil {
+			return nil, err
+		}
+	}
+
+	return Open(root)
+}
+
+// Open opens an existing image layout at root.
+func Open(root string) (*Layout, error) {
+
+	content, err := ioutil.ReadFile(filepath.Join(root, v1.ImageLayoutFile))
+	if err != nil {
+		return nil, fmt.Errorf("%s is not an OCI image layout: %v", root, err)
+	}
+
+	imageLayout := v1.ImageLayout{}
+	if err := json.Unmarshal(content, &imageLayout); err != nil {
+		return nil, err
+	}
+
+	if imageLayout.Version != v1.ImageLayoutVersion {
+		return nil, fmt.Errorf("unsupported OCI image layout version %q", imageLayout.Version)
+	}
+
+	return &Layout{root: root}, nil
+}
+
+func (l *Layout) blobPath(dgst digest.Digest) string {
+	return filepath.Join(l.root, "blobs", dgst.Algorithm().String(), dgst.Encoded())
+}
+
+// HasBlob reports whether the blob is already stored with the expected size
+// and content, so interrupted transfers can skip it when they resume.
+func (l *Layout) HasBlob(desc v1.Descriptor) (bool, error) {
+
+	file, err := os.Open(l.blobPath(desc.Digest))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return false, err
+	}
+	if info.Size() != desc.Size {
+		return false, nil
+	}
+
+	verifier := desc.Digest.Verifier()
+	if _, err := io.Copy(verifier, file); err != nil {
+		return false, err
+	}
+
+	return verifier.Verified(), nil
+}
+
+// WriteBlob stores the content of a blob, failing if it does not match the descriptor.
+func (l *Layout) WriteBlob(desc v1.Descriptor, content io.Reader) error {
+
+	if err := desc.Digest.Validate(); err != nil {
+		return err
+	}
+
+	blobPath := l.blobPath(desc.Digest)
+	if err := os.MkdirAll(filepath.Dir(blobPath), 0755); err != nil {
+		return err
+	}
+
+	// write next to the destination so the rename is atomic
+	file, err := ioutil.TempFile(filepath.Dir(blobPath), desc.Digest.Encoded()+".partial")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	verifier := desc.Digest.Verifier()
+	size, err := io.Copy(io.MultiWriter(file, verifier), content)
+	if err != nil {
+		return err
+	}
+
+	if size != desc.Size {
+		return fmt.Errorf("blob %s: expected %d bytes, got %d", desc.Digest, desc.Size, size)
+	}
+	if !verifier.Verified() {
+		return fmt.Errorf("blob %s: content does not match digest", desc.Digest)
+	}
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), blobPath)
+}
+
+// OpenBlob opens a stored blob for reading.
+func (l *Layout) OpenBlob(dgst digest.Digest) (io.ReadCloser, error) {
+	return os.Open(l.blobPath(dgst))
+}
+
+// ReadBlob returns the whole content of a stored blob.
+func (l *Layout) ReadBlob(dgst digest.Digest) ([]byte, error) {
+	return ioutil.ReadFile(l.blobPath(dgst))
+}
+
+// Index returns the top level index.json, which is empty for a new layout.
+func (l *Layout) Index() (v1.Index, error) {
+
+	index := v1.Index{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+	}
+
+	content, err := ioutil.ReadFile(filepath.Join(l.root, "index.json"))
+	if os.IsNotExist(err) {
+		return index, nil
+	}
+	if err != nil {
+		return index, err
+	}
+
+	if err := json.Unmarshal(content, &index); err != nil {
+		return index, err
+	}
+
+	return index, nil
+}
+
+// AddToIndex records desc in index.json. When refName is set, it replaces any
+// manifest previously stored under the same name.
+func (l *Layout) AddToIndex(desc v1.Descriptor, refName string) error {
+
+	index, err := l.Index()
+	if err != nil {
+		return err
+	}
+
+	if refName != "" {
+		if desc.Annotations == nil {
+			desc.Annotations = map[string]string{}
+		}
+		desc.Annotations[v1.AnnotationRefName] = refName
+	}
+
+	manifests := []v1.Descriptor{}
+	for _, existing := range index.Manifests {
+		if refName != "" && existing.Annotations[v1.AnnotationRefName] == refName {
+			continue
+		}
+		if refName == "" && existing.Digest == desc.Digest && existing.Annotations[v1.AnnotationRefName] == "" {
+			continue
+		}
+		manifests = append(manifests, existing)
+	}
+	index.Manifests = append(manifests, desc)
+
+	content, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filepath.Join(l.root, "index.json"), content, 0644)
+}
@@ -0,0 +1,120 @@
+package layout
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	digest "github.com/opencontainers/go-digest"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+func descriptorOf(content string) v1.Descriptor {
+	return v1.Descriptor{
+		MediaType: v1.MediaTypeImageLayer,
+		Digest:    digest.FromString(content),
+		Size:      int64(len(content)),
+	}
+}
+
+func TestNewCreatesLayout(t *testing.T) {
+
+	root := filepath.Join(t.TempDir(), "image")
+	if _, err := New(root); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(filepath.Join(root, v1.ImageLayoutFile)); err != nil {
+		t.Errorf("new layout has no %s: %v", v1.ImageLayoutFile, err)
+	}
+	if _, err := Open(root); err != nil {
+		t.Errorf("Open(%s): %v", root, err)
+	}
+	if _, err := Open(t.TempDir()); err == nil {
+		t.Error("Open accepted a directory without an oci-layout file")
+	}
+}
+
+func TestWriteBlob(t *testing.T) {
+
+	l, err := New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	desc := descriptorOf("layer")
+	if exists, err := l.HasBlob(desc); err != nil || exists {
+		t.Fatalf("HasBlob before writing = %v, %v", exists, err)
+	}
+
+	if err := l.WriteBlob(desc, strings.NewReader("other")); err == nil {
+		t.Error("WriteBlob accepted content that does not match the digest")
+	}
+	if err := l.WriteBlob(desc, strings.NewReader("layer and more")); err == nil {
+		t.Error("WriteBlob accepted content longer than the descriptor")
+	}
+	if exists, _ := l.HasBlob(desc); exists {
+		t.Error("a rejected blob was kept")
+	}
+
+	if err := l.WriteBlob(desc, strings.NewReader("layer")); err != nil {
+		t.Fatal(err)
+	}
+	if exists, err := l.HasBlob(desc); err != nil || !exists {
+		t.Errorf("HasBlob after writing = %v, %v", exists, err)
+	}
+	content, err := l.ReadBlob(desc.Digest)
+	if err != nil || !bytes.Equal(content, []byte("layer")) {
+		t.Errorf("ReadBlob = %q, %v", content, err)
+	}
+
+	// an interrupted transfer leaves a blob that must be fetched again
+	if err := ioutil.WriteFile(l.blobPath(desc.Digest), []byte("lay"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if exists, _ := l.HasBlob(desc); exists {
+		t.Error("HasBlob accepted a truncated blob")
+	}
+}
+
+func TestAddToIndex(t *testing.T) {
+
+	l, err := New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, second := descriptorOf("first"), descriptorOf("second")
+	for _, add := range []struct {
+		desc    v1.Descriptor
+		refName string
+	}{
+		{first, "1.0"},
+		{second, "1.0"},
+		{first, ""},
+		{first, ""},
+	} {
+		if err := l.AddToIndex(add.desc, add.refName); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	index, err := l.Index()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the ref name moved to the second manifest, the untagged one is recorded once
+	if len(index.Manifests) != 2 {
+		t.Fatalf("index has %d manifests, want 2: %+v", len(index.Manifests), index.Manifests)
+	}
+	if index.Manifests[0].Digest != second.Digest || index.Manifests[0].Annotations[v1.AnnotationRefName] != "1.0" {
+		t.Errorf("index.Manifests[0] = %+v, want %s as 1.0", index.Manifests[0], second.Digest)
+	}
+	if index.Manifests[1].Digest != first.Digest || index.Manifests[1].Annotations[v1.AnnotationRefName] != "" {
+		t.Errorf("index.Manifests[1] = %+v, want %s without a ref name", index.Manifests[1], first.Digest)
+	}
+}
@@ -0,0 +1,264 @@
+package registry
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	digest "github.com/opencontainers/go-digest"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// acrPageSize is the number of tags or manifests asked for per page of the ACR metadata API
+const acrPageSize = 100
+
+// aadIssuerHosts are the hosts of the issuers of Azure Active Directory
+// access tokens in the public and sovereign clouds
+var aadIssuerHosts = map[string]bool{
+	"sts.windows.net":           true,
+	"login.microsoftonline.com": true,
+	"login.microsoftonline.us":  true,
+	"sts.chinacloudapi.cn":      true,
+	"login.chinacloudapi.cn":    true,
+}
+
+type acrTagsResponse struct {
+	Tags []acrTag `json:"tags"`
+}
+
+type acrTag struct {
+	Name        string        `json:"name"`
+	Digest      digest.Digest `json:"digest"`
+	CreatedTime time.Time     `json:"createdTime"`
+}
+
+type acrManifestsResponse struct {
+	Manifests []acrManifest `json:"manifests"`
+}
+
+type acrManifest struct {
+	Digest          digest.Digest `json:"digest"`
+	ImageSize       int64         `json:"imageSize"`
+	CreatedTime     time.Time     `json:"createdTime"`
+	MediaType       string        `json:"mediaType"`
+	ConfigMediaType string        `json:"configMediaType"`
+}
+
+type acrExchangeResponse struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// IsACR reports whether the registry is an Azure Container Registry
+func IsACR(hostname string) bool {
+	if host, _, err := net.SplitHostPort(hostname); err == nil {
+		hostname = host
+	}
+	return strings.HasSuffix(strings.ToLower(hostname), ".azurecr.io")
+}
+
+// isAADToken reports whether the secret is an Azure Active Directory access
+// token, which ACR only accepts after exchanging it for a refresh token. The
+// token is not verified, the registry does that.
+func isAADToken(secret string) bool {
+
+	claims, ok := jwtClaims(secret)
+	if !ok {
+		return false
+	}
+
+	issuer, err := url.Parse(claims.Issuer)
+	if err != nil {
+		return false
+	}
+
+	return aadIssuerHosts[issuer.Host]
+}
+
+type aadClaims struct {
+	Issuer   string `json:"iss"`
+	TenantID string `json:"tid"`
+}
+
+func jwtClaims(token string) (aadClaims, bool) {
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return aadClaims{}, false
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return aadClaims{}, false
+	}
+
+	claims := aadClaims{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return aadClaims{}, false
+	}
+
+	return claims, true
+}
+
+// acrRefreshToken exchanges the AAD access token of the transport for an ACR
+// refresh token at /oauth2/exchange, next to the token realm. The refresh
+// token is kept for the life of the transport.
+func (t *TokenTransport) acrRefreshToken(realm *url.URL, service string) (string, error) {
+
+	t.mu.Lock()
+	refreshToken := t.refreshToken
+	t.mu.Unlock()
+	if refreshToken != "" {
+		return refreshToken, nil
+	}
+
+	exchange := *realm
+	exchange.Path = "/oauth2/exchange"
+	exchange.RawQuery = ""
+
+	form := url.Values{}
+	form.Set("grant_type", "access_token")
+	form.Set("service", service)
+	form.Set("access_token", t.Password)
+	if claims, ok := jwtClaims(t.Password); ok && claims.TenantID != "" {
+		form.Set("tenant", claims.TenantID)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, exchange.String(), strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := (&http.Client{Transport: t.Transport}).Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", &HTTPStatusError{Response: resp, Body: body}
+	}
+
+	exchangeResp := acrExchangeResponse{}
+	if err := json.Unmarshal(body, &exchangeResp); err != nil {
+		return "", err
+	}
+	if exchangeResp.RefreshToken == "" {
+		return "", fmt.Errorf("%s returned no refresh token", exchange.Host)
+	}
+
+	t.mu.Lock()
+	t.refreshToken = exchangeResp.RefreshToken
+	t.mu.Unlock()
+
+	return exchangeResp.RefreshToken, nil
+}
+
+// getACRImageDataList describes the tags of a repository from the ACR
+// metadata API, a few paged requests instead of a manifest fetch per tag.
+// Created is when the manifest was pushed, and Layers and Blobs are empty.
+func (rc *registryClient) getACRImageDataList(hostname string, repo string) ([]ImageData, error) {
+
+	manifests := map[digest.Digest]acrManifest{}
+	err := rc.getACRPages(hostname, fmt.Sprintf("https://%s/acr/v1/%s/_manifests?n=%d", hostname, repo, acrPageSize), func(body []byte) error {
+		manifestsResp := acrManifestsResponse{}
+		if err := json.Unmarshal(body, &manifestsResp); err != nil {
+			return err
+		}
+		for _, manifest := range manifestsResp.Manifests {
+			manifests[manifest.Digest] = manifest
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	returnImageData := []ImageData{}
+	err = rc.getACRPages(hostname, fmt.Sprintf("https://%s/acr/v1/%s/_tags?n=%d", hostname, repo, acrPageSize), func(body []byte) error {
+		tagsResp := acrTagsResponse{}
+		if err := json.Unmarshal(body, &tagsResp); err != nil {
+			return err
+		}
+
+		for _, tag := range tagsResp.Tags {
+			if rc.tagFilter != nil && !rc.tagFilter(tag.Name) {
+				continue
+			}
+
+			manifest := manifests[tag.Digest]
+			imageData := ImageData{
+				Name:      repo,
+				Tag:       tag.Name,
+				Hostname:  hostname,
+				Digest:    tag.Digest,
+				MediaType: manifest.MediaType,
+				Size:      manifest.ImageSize,
+				Created:   manifest.CreatedTime,
+			}
+			if imageData.Created.IsZero() {
+				imageData.Created = tag.CreatedTime
+			}
+
+			imageData.ArtifactKind = acrArtifactKind(manifest)
+			if kind, subject, ok := ParseArtifactTag(tag.Name); ok {
+				if imageData.ArtifactKind == "" || imageData.ArtifactKind == ArtifactOther {
+					imageData.ArtifactKind = kind
+				}
+				imageData.Subject = subject
+			}
+			if imageData.IsArtifact() {
+				// artifacts have no creation time of their own, like GetImageData
+				imageData.Created = time.Time{}
+			}
+
+			returnImageData = append(returnImageData, imageData)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return returnImageData, nil
+}
+
+// acrArtifactKind tells artifacts apart by the config media type, the way
+// Manifest.artifact does when it has the whole manifest.
+func acrArtifactKind(manifest acrManifest) string {
+
+	if kind, ok := artifactMediaTypes[manifest.ConfigMediaType]; ok {
+		return kind
+	}
+	if manifest.ConfigMediaType == "" || manifest.MediaType == v1.MediaTypeImageIndex {
+		return ""
+	}
+	if !isImageConfig(manifest.ConfigMediaType) {
+		return ArtifactOther
+	}
+
+	return ""
+}
+
+// getACRPages requests every page of an ACR list, following the next links.
+func (rc *registryClient) getACRPages(hostname string, query string, handle func(body []byte) error) error {
+
+	httpClient, err := rc.httpClient(hostname)
+	if err != nil {
+		return err
+	}
+
+	return getPages(httpClient, query, nil, func(body []byte) (string, error) {
+		return "", handle(body)
+	})
+}
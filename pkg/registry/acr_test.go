@@ -0,0 +1,244 @@
+package registry
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	digest "github.com/opencontainers/go-digest"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// testACRHostname is served by the test server, as IsACR needs an azurecr.io host
+const testACRHostname = "test.azurecr.io"
+
+// newACRTestClient returns a client of testACRHostname that authenticates
+// with the credential, describing tags from the ACR metadata API.
+func newACRTestClient(transport http.RoundTripper, username string, password string) *registryClient {
+	return &registryClient{
+		hostname: testACRHostname,
+		httpClientMap: map[string]*http.Client{
+			testACRHostname: {
+				Transport: &ErrorTransport{
+					Transport: &TokenTransport{
+						Transport: transport,
+						URL:       testACRHostname,
+						Username:  username,
+						Password:  password,
+					},
+				},
+			},
+		},
+		tagMetadata: true,
+		credentials: map[string]credential{},
+		adapters:    map[string]Adapter{},
+	}
+}
+
+// testAADToken is an access token as issued by Azure Active Directory,
+// without a valid signature as only the registry verifies it.
+func testAADToken() string {
+	encode := func(v string) string { return base64.RawURLEncoding.EncodeToString([]byte(v)) }
+	return encode(`{"alg":"RS256","typ":"JWT"}`) + "." + encode(`{"iss":"https://sts.windows.net/tenant-id/","tid":"tenant-id"}`) + ".signature"
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func TestACRRefreshToken(t *testing.T) {
+
+	aadToken := testAADToken()
+	exchanges := 0
+
+	transport := newTestTransport(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/oauth2/exchange":
+			exchanges++
+			r.ParseForm()
+			if r.PostForm.Get("grant_type") != "access_token" || r.PostForm.Get("service") != testACRHostname ||
+				r.PostForm.Get("access_token") != aadToken || r.PostForm.Get("tenant") != "tenant-id" {
+				t.Errorf("unexpected exchange request %v", r.PostForm)
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			writeJSON(w, map[string]string{"refresh_token": "acr-refresh-token"})
+
+		case "/oauth2/token":
+			r.ParseForm()
+			if r.PostForm.Get("grant_type") != "refresh_token" || r.PostForm.Get("refresh_token") != "acr-refresh-token" {
+				t.Errorf("unexpected token request %v", r.PostForm)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			writeJSON(w, map[string]string{"access_token": "acr-access-token"})
+
+		default:
+			if r.Header.Get("Authorization") != "Bearer acr-access-token" {
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="https://%s/oauth2/token",service="%s"`, testACRHostname, testACRHostname))
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			writeJSON(w, tagsResponse{Tags: []string{"v1"}})
+		}
+	}))
+
+	rc := newACRTestClient(transport, "", aadToken)
+
+	// tokens are per repository, the refresh token is exchanged once
+	for _, repo := range []string{"app", "worker"} {
+		tags, err := rc.GetTags(testACRHostname, repo)
+		if err != nil {
+			t.Fatalf("GetTags(%q): %v", repo, err)
+		}
+		if len(tags) != 1 || tags[0] != "v1" {
+			t.Errorf("GetTags(%q) = %v, want [v1]", repo, tags)
+		}
+	}
+	if exchanges != 1 {
+		t.Errorf("AAD token exchanged %d times, want once", exchanges)
+	}
+}
+
+func TestACRRefreshTokenError(t *testing.T) {
+
+	transport := newTestTransport(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/oauth2/exchange":
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"errors":[{"code":"UNAUTHORIZED","message":"aad token expired"}]}`))
+		case "/oauth2/token":
+			t.Error("token requested without a refresh token")
+			w.WriteHeader(http.StatusUnauthorized)
+		default:
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="https://%s/oauth2/token",service="%s"`, testACRHostname, testACRHostname))
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+
+	rc := newACRTestClient(transport, "", testAADToken())
+
+	_, err := rc.GetTags(testACRHostname, "app")
+	if !IsUnauthorized(err) {
+		t.Fatalf("GetTags error = %v, want a 401", err)
+	}
+	if !strings.Contains(err.Error(), "aad token expired") {
+		t.Errorf("GetTags error = %v, want the error of the exchange", err)
+	}
+}
+
+func TestACRImageDataList(t *testing.T) {
+
+	created := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+	image := digest.FromString("image")
+	index := digest.FromString("index")
+	signature := digest.FromString("signature")
+
+	transport := newTestTransport(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		last := r.URL.Query().Get("last")
+		switch r.URL.Path {
+		case "/acr/v1/app/_manifests":
+			if last == "" {
+				w.Header().Set("Link", fmt.Sprintf(`</acr/v1/app/_manifests?last=%s&n=%d>; rel="next"`, image, acrPageSize))
+				writeJSON(w, acrManifestsResponse{Manifests: []acrManifest{
+					{Digest: image, ImageSize: 1000, CreatedTime: created, MediaType: v1.MediaTypeImageManifest, ConfigMediaType: v1.MediaTypeImageConfig},
+				}})
+				return
+			}
+			writeJSON(w, acrManifestsResponse{Manifests: []acrManifest{
+				{Digest: index, ImageSize: 3000, CreatedTime: created.Add(time.Hour), MediaType: v1.MediaTypeImageIndex},
+				{Digest: signature, ImageSize: 100, CreatedTime: created.Add(2 * time.Hour), MediaType: v1.MediaTypeImageManifest, ConfigMediaType: v1.MediaTypeImageConfig},
+			}})
+
+		case "/acr/v1/app/_tags":
+			if last == "" {
+				w.Header().Set("Link", fmt.Sprintf(`</acr/v1/app/_tags?last=v1&n=%d>; rel="next"`, acrPageSize))
+				writeJSON(w, acrTagsResponse{Tags: []acrTag{
+					{Name: "v1", Digest: image},
+					{Name: "v2", Digest: index},
+				}})
+				return
+			}
+			writeJSON(w, acrTagsResponse{Tags: []acrTag{
+				{Name: image.Algorithm().String() + "-" + image.Encoded() + ".sig", Digest: signature},
+			}})
+
+		default:
+			t.Errorf("unexpected request %s", r.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	images, err := newACRTestClient(transport, "user", "password").GetImageDataList(testACRHostname, "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []ImageData{
+		{Tag: "v1", Digest: image, Size: 1000, Created: created, MediaType: v1.MediaTypeImageManifest},
+		{Tag: "v2", Digest: index, Size: 3000, Created: created.Add(time.Hour), MediaType: v1.MediaTypeImageIndex},
+		{Tag: "sha256-" + image.Encoded() + ".sig", Digest: signature, Size: 100, MediaType: v1.MediaTypeImageManifest, ArtifactKind: ArtifactSignature, Subject: image},
+	}
+	if len(images) != len(want) {
+		t.Fatalf("GetImageDataList returned %d images, want %d: %+v", len(images), len(want), images)
+	}
+	for i, got := range images {
+		if got.Name != "app" || got.Hostname != testACRHostname || got.Tag != want[i].Tag || got.Digest != want[i].Digest ||
+			got.Size != want[i].Size || !got.Created.Equal(want[i].Created) || got.MediaType != want[i].MediaType ||
+			got.ArtifactKind != want[i].ArtifactKind || got.Subject != want[i].Subject {
+			t.Errorf("image %d = %+v, want %+v", i, got, want[i])
+		}
+	}
+}
+
+func TestACRImageDataListFallback(t *testing.T) {
+
+	created := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+	config := []byte(fmt.Sprintf(`{"created": %q}`, created.Format(time.RFC3339)))
+	configDigest := digest.FromBytes(config)
+	manifest := []byte(fmt.Sprintf(`{"schemaVersion": 2, "mediaType": %q, "config": {"mediaType": %q, "digest": %q, "size": %d}, "layers": []}`,
+		v1.MediaTypeImageManifest, v1.MediaTypeImageConfig, configDigest, len(config)))
+
+	for _, status := range []int{http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+
+			transport := newTestTransport(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case strings.HasPrefix(r.URL.Path, "/acr/v1/"):
+					w.WriteHeader(status)
+				case r.URL.Path == "/v2/app/tags/list":
+					writeJSON(w, tagsResponse{Tags: []string{"v1"}})
+				case r.URL.Path == "/v2/app/manifests/v1":
+					w.Header().Set("Content-Type", v1.MediaTypeImageManifest)
+					w.Write(manifest)
+				case r.URL.Path == "/v2/app/blobs/"+configDigest.String():
+					w.Write(config)
+				default:
+					t.Errorf("unexpected request %s", r.URL)
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+
+			images, err := newACRTestClient(transport, "user", "password").GetImageDataList(testACRHostname, "app")
+
+			// only a missing permission or API falls back to the manifests
+			if status == http.StatusInternalServerError {
+				if err == nil {
+					t.Fatalf("GetImageDataList succeeded with the metadata API failing, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(images) != 1 || images[0].Tag != "v1" || !images[0].Created.Equal(created) {
+				t.Errorf("GetImageDataList = %+v, want v1 created %s", images, created)
+			}
+		})
+	}
+}
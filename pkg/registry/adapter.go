@@ -0,0 +1,249 @@
+package registry
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// ErrNotSupported is returned by an adapter that cannot list the
+// repositories of a registry, e.g. without a credential naming a namespace.
+// The distribution catalog is used instead.
+var ErrNotSupported = errors.New("not supported by the registry adapter")
+
+// Adapter lists repositories through the native API of a registry vendor,
+// for registries that restrict or do not implement /v2/_catalog. Tags and
+// manifests are always read through the distribution API.
+type Adapter interface {
+	// Name names the vendor in messages
+	Name() string
+	// Detect reports whether the adapter serves the registry. It may probe
+	// the registry, and is called once per registry.
+	Detect(req AdapterRequest) bool
+	// Repositories lists the repositories the credential can see
+	Repositories(req AdapterRequest) ([]Repository, error)
+}
+
+// AdapterRequest is what an adapter needs to call the API of a registry
+type AdapterRequest struct {
+	Hostname string
+	Username string
+	Password string
+	// Probe lets Detect send requests to registries without a well-known
+	// hostname to find out which vendor runs them
+	Probe bool
+	// Client sends requests as they are, without the credential, and turns
+	// error responses into HTTPStatusError
+	Client *http.Client
+}
+
+// Repository is a repository as listed by a registry
+type Repository struct {
+	Name string
+	// PushedAt is when an image was last pushed, zero when the registry does not tell
+	PushedAt time.Time
+	// PullCount is -1 when the registry does not count pulls
+	PullCount int64
+}
+
+// adapters are asked in order, the first one detecting a registry serves it
+var adapters = []Adapter{
+	&dockerHubAdapter{},
+	&ghcrAdapter{},
+	&gitlabAdapter{},
+	&quayAdapter{},
+	&harborAdapter{},
+}
+
+// RegisterAdapter adds an adapter that is asked before the built-in ones
+func RegisterAdapter(adapter Adapter) {
+	adapters = append([]Adapter{adapter}, adapters...)
+}
+
+// adapterTimeout bounds a request to a vendor API, including reading its body
+const adapterTimeout = 30 * time.Second
+
+// adapterHTTPClient calls vendor APIs, which authenticate differently from the distribution API
+var adapterHTTPClient = &http.Client{
+	Transport: &ErrorTransport{Transport: http.DefaultTransport},
+	Timeout:   adapterTimeout,
+}
+
+func (rc *registryClient) adapterRequest(hostname string) AdapterRequest {
+	credential := rc.credentials[hostname]
+	return AdapterRequest{
+		Hostname: hostname,
+		Username: credential.username,
+		Password: credential.password,
+		Probe:    rc.probeRegistries,
+		Client:   adapterHTTPClient,
+	}
+}
+
+// adapter returns the adapter serving the registry, or nil for registries
+// that only have the distribution API.
+func (rc *registryClient) adapter(hostname string) Adapter {
+
+	if adapter, ok := rc.adapters[hostname]; ok {
+		return adapter
+	}
+
+	var found Adapter
+	for _, adapter := range adapters {
+		if adapter.Detect(rc.adapterRequest(hostname)) {
+			found = adapter
+			break
+		}
+	}
+	rc.adapters[hostname] = found
+
+	return found
+}
+
+// repositories lists the repositories of a registry through its adapter,
+// falling back to the distribution catalog when there is none or it fails.
+func (rc *registryClient) repositories(hostname string) ([]Repository, error) {
+
+	if adapter := rc.adapter(hostname); adapter != nil {
+		repos, err := adapter.Repositories(rc.adapterRequest(hostname))
+		if err == nil {
+			return repos, nil
+		}
+		if err != ErrNotSupported {
+			fmt.Fprintf(os.Stderr, "WARNING: listing the repositories of %s through the %s API: %v\n", hostname, adapter.Name(), err)
+		}
+	}
+
+	names, err := rc.catalog(hostname)
+	if err != nil {
+		return nil, err
+	}
+
+	repos := []Repository{}
+	for _, name := range names {
+		repos = append(repos, Repository{Name: name, PullCount: -1})
+	}
+
+	return repos, nil
+}
+
+// getPages requests a list and the pages after it. The next page is the
+// rel="next" Link header, or else the URL handle returns from the body. The
+// header is only sent to the scheme and host of the first query, so that a
+// next link cannot hand the credential to another server.
+func getPages(client *http.Client, query string, header http.Header, handle func(body []byte) (string, error)) error {
+
+	first, err := url.Parse(query)
+	if err != nil {
+		return err
+	}
+
+	for query != "" {
+		req, err := http.NewRequest(http.MethodGet, query, nil)
+		if err != nil {
+			return err
+		}
+		if req.URL.Scheme == first.Scheme && req.URL.Host == first.Host {
+			for name, values := range header {
+				req.Header[name] = values
+			}
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		next, err := handle(body)
+		if err != nil {
+			return err
+		}
+		if link := nextLink(resp); link != "" {
+			next = link
+		}
+		if next == "" {
+			return nil
+		}
+
+		nextURL, err := req.URL.Parse(next)
+		if err != nil {
+			return err
+		}
+		query = nextURL.String()
+	}
+
+	return nil
+}
+
+// nextLink returns the target of the rel="next" Link header, as registries
+// send it with paged lists, e.g. </acr/v1/repo/_tags?last=v2&n=100>; rel="next"
+func nextLink(resp *http.Response) string {
+
+	for _, header := range resp.Header.Values("Link") {
+		for _, link := range strings.Split(header, ",") {
+			parts := strings.Split(link, ";")
+			target := strings.TrimSpace(parts[0])
+			if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			for _, param := range parts[1:] {
+				if strings.Replace(strings.TrimSpace(param), " ", "", -1) == `rel="next"` {
+					return strings.Trim(target, "<>")
+				}
+			}
+		}
+	}
+
+	return ""
+}
+
+// getJSON requests a single resource of a vendor API
+func getJSON(client *http.Client, query string, header http.Header, v interface{}) error {
+
+	req, err := http.NewRequest(http.MethodGet, query, nil)
+	if err != nil {
+		return err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// basicHeader authenticates requests to vendor APIs that take the registry credential
+func basicHeader(username string, password string) http.Header {
+	header := http.Header{}
+	if username != "" {
+		header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(username+":"+password)))
+	}
+	return header
+}
+
+// bearerHeader authenticates requests to vendor APIs that take a token
+func bearerHeader(token string) http.Header {
+	header := http.Header{}
+	if token != "" {
+		header.Set("Authorization", "Bearer "+token)
+	}
+	return header
+}
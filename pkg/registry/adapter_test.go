@@ -0,0 +1,200 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync/atomic"
+	"testing"
+)
+
+// useAdapterTransport sends the requests of the adapters to handler
+func useAdapterTransport(t *testing.T, handler http.Handler) {
+
+	old := adapterHTTPClient
+	adapterHTTPClient = &http.Client{
+		Transport: &ErrorTransport{Transport: newTestTransport(t, handler)},
+		Timeout:   adapterTimeout,
+	}
+	t.Cleanup(func() { adapterHTTPClient = old })
+}
+
+// fakeAdapter serves every registry with a fixed list
+type fakeAdapter struct {
+	repos []Repository
+	err   error
+}
+
+func (a *fakeAdapter) Name() string {
+	return "fake"
+}
+
+func (a *fakeAdapter) Detect(req AdapterRequest) bool {
+	return true
+}
+
+func (a *fakeAdapter) Repositories(req AdapterRequest) ([]Repository, error) {
+	return a.repos, a.err
+}
+
+func TestNextLink(t *testing.T) {
+
+	tests := []struct {
+		links []string
+		want  string
+	}{
+		{nil, ""},
+		{[]string{`</v2/_catalog?last=b&n=2>; rel="next"`}, "/v2/_catalog?last=b&n=2"},
+		{[]string{`<https://example.com/a>; rel="prev", <https://example.com/b>; rel = "next"`}, "https://example.com/b"},
+		{[]string{`<https://example.com/a>; rel="prev"`, `<https://example.com/b>; rel="next"`}, "https://example.com/b"},
+		{[]string{`https://example.com/b; rel="next"`}, ""},
+	}
+
+	for _, test := range tests {
+		resp := &http.Response{Header: http.Header{"Link": test.links}}
+		if got := nextLink(resp); got != test.want {
+			t.Errorf("nextLink(%q) = %q, want %q", test.links, got, test.want)
+		}
+	}
+}
+
+// TestGetPages checks that pages are followed through the Link header and
+// the body, and that the credential stays on the host of the first query.
+func TestGetPages(t *testing.T) {
+
+	authorized := map[string]bool{}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page := r.URL.Query().Get("page")
+		authorized[r.Host+" "+page] = r.Header.Get("Authorization") != ""
+		switch page {
+		case "1":
+			w.Header().Set("Link", `</list?page=2>; rel="next"`)
+			writeJSON(w, map[string]string{"item": "a"})
+		case "2":
+			writeJSON(w, map[string]string{"item": "b", "next": "https://other.example.com/list?page=3"})
+		case "3":
+			writeJSON(w, map[string]string{"item": "c"})
+		default:
+			t.Errorf("unexpected request %s", r.URL)
+			http.NotFound(w, r)
+		}
+	})
+	client := &http.Client{Transport: &ErrorTransport{Transport: newTestTransport(t, handler)}}
+
+	items := []string{}
+	err := getPages(client, fmt.Sprintf("https://%s/list?page=1", testHostname), basicHeader("user", "secret"), func(body []byte) (string, error) {
+		page := map[string]string{}
+		if err := json.Unmarshal(body, &page); err != nil {
+			return "", err
+		}
+		items = append(items, page["item"])
+		return page["next"], nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"a", "b", "c"}; !reflect.DeepEqual(items, want) {
+		t.Errorf("items = %v, want %v", items, want)
+	}
+	want := map[string]bool{
+		testHostname + " 1":   true,
+		testHostname + " 2":   true,
+		"other.example.com 3": false,
+	}
+	if !reflect.DeepEqual(authorized, want) {
+		t.Errorf("requests sent with a credential = %v, want %v", authorized, want)
+	}
+}
+
+// TestHarborDetect checks that Harbor is only probed when allowed, and
+// once per host.
+func TestHarborDetect(t *testing.T) {
+
+	probes := int32(0)
+	useAdapterTransport(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v2.0/systeminfo" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		atomic.AddInt32(&probes, 1)
+		if r.Host != testHostname {
+			http.NotFound(w, r)
+			return
+		}
+		writeJSON(w, harborSystemInfo{AuthMode: "db_auth"})
+	}))
+
+	adapter := &harborAdapter{}
+
+	if adapter.Detect(AdapterRequest{Hostname: testHostname, Client: adapterHTTPClient}) {
+		t.Errorf("Detect() without probing = true, want false")
+	}
+	if probes != 0 {
+		t.Errorf("Detect() without probing sent %d requests", probes)
+	}
+
+	tests := []struct {
+		hostname string
+		want     bool
+	}{
+		{testHostname, true},
+		{testHostname, true},
+		{"other.example.com", false},
+		{"other.example.com", false},
+	}
+	for _, test := range tests {
+		if got := adapter.Detect(AdapterRequest{Hostname: test.hostname, Probe: true, Client: adapterHTTPClient}); got != test.want {
+			t.Errorf("Detect(%q) = %v, want %v", test.hostname, got, test.want)
+		}
+	}
+	if probes != 2 {
+		t.Errorf("Detect() sent %d requests, want one per host", probes)
+	}
+}
+
+// TestRepositories checks that the catalog is used when no adapter serves
+// the registry or the adapter cannot list it, without probing by default.
+func TestRepositories(t *testing.T) {
+
+	useAdapterTransport(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected vendor API request %s", r.URL)
+		http.NotFound(w, r)
+	}))
+
+	catalog := []Repository{{Name: "app", PullCount: -1}, {Name: "web", PullCount: -1}}
+	listed := []Repository{{Name: "app", PullCount: 3}}
+
+	tests := []struct {
+		name    string
+		adapter Adapter
+		want    []Repository
+	}{
+		{"no adapter", nil, catalog},
+		{"adapter", &fakeAdapter{repos: listed}, listed},
+		{"not supported", &fakeAdapter{err: ErrNotSupported}, catalog},
+		{"failing", &fakeAdapter{err: fmt.Errorf("forbidden")}, catalog},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rc := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/v2/_catalog" {
+					t.Errorf("unexpected request %s", r.URL)
+				}
+				writeJSON(w, map[string][]string{"repositories": {"app", "web"}})
+			}))
+			if test.adapter != nil {
+				rc.adapters[testHostname] = test.adapter
+			}
+
+			repos, err := rc.repositories(testHostname)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(repos, test.want) {
+				t.Errorf("repositories() = %v, want %v", repos, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package registry
+
+import (
+	"encoding/json"
+	"regexp"
+
+	"github.com/docker/distribution/manifest/schema2"
+	digest "github.com/opencontainers/go-digest"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// Kinds of artifacts that are stored next to the images they describe
+const (
+	ArtifactSignature   = "signature"
+	ArtifactAttestation = "attestation"
+	ArtifactSBOM        = "sbom"
+	ArtifactOther       = "artifact"
+)
+
+// artifactTagRegexp matches the tags cosign pushes for the image with digest
+// <algorithm>:<hex>, e.g. sha256-<hex>.sig
+var artifactTagRegexp = regexp.MustCompile(`^(sha256|sha512)-([a-f0-9]+)\.(sig|att|sbom)$`)
+
+var artifactTagSuffixes = map[string]string{
+	"sig":  ArtifactSignature,
+	"att":  ArtifactAttestation,
+	"sbom": ArtifactSBOM,
+}
+
+// artifactMediaTypes maps the artifact, config and layer media types of
+// signatures, attestations and SBOMs to their kind.
+var artifactMediaTypes = map[string]string{
+	"application/vnd.dev.cosign.simplesigning.v1+json": ArtifactSignature,
+	"application/vnd.dev.cosign.artifact.sig.v1+json":  ArtifactSignature,
+	"application/vnd.cncf.notary.signature":            ArtifactSignature,
+	"application/vnd.dsse.envelope.v1+json":            ArtifactAttestation,
+	"application/vnd.in-toto+json":                     ArtifactAttestation,
+	"text/spdx":                                        ArtifactSBOM,
+	"text/spdx+json":                                   ArtifactSBOM,
+	"application/spdx+json":                            ArtifactSBOM,
+	"application/vnd.cyclonedx+json":                   ArtifactSBOM,
+	"application/vnd.cyclonedx+xml":                    ArtifactSBOM,
+	"application/vnd.syft+json":                        ArtifactSBOM,
+}
+
+// ParseArtifactTag recognizes the sha256-<hex>.sig, .att and .sbom tag
+// convention and returns the kind of artifact and the digest of its subject.
+func ParseArtifactTag(tag string) (string, digest.Digest, bool) {
+
+	match := artifactTagRegexp.FindStringSubmatch(tag)
+	if match == nil {
+		return "", "", false
+	}
+
+	subject := digest.NewDigestFromEncoded(digest.Algorithm(match[1]), match[2])
+	if subject.Validate() != nil {
+		return "", "", false
+	}
+
+	return artifactTagSuffixes[match[3]], subject, true
+}
+
+// artifactManifest holds the fields of an image manifest that tell an
+// artifact apart from a runnable image
+type artifactManifest struct {
+	ArtifactType string          `json:"artifactType"`
+	Config       v1.Descriptor   `json:"config"`
+	Layers       []v1.Descriptor `json:"layers"`
+	Subject      *v1.Descriptor  `json:"subject"`
+}
+
+// artifact reports the kind of artifact the manifest holds and the digest of
+// the image it refers to, or an empty kind for images and indexes.
+func (m Manifest) artifact() (string, digest.Digest) {
+
+	if m.IsIndex() || m.IsSchema1() {
+		return "", ""
+	}
+
+	manifest := artifactManifest{}
+	if err := json.Unmarshal(m.Content, &manifest); err != nil {
+		return "", ""
+	}
+
+	subject := digest.Digest("")
+	if manifest.Subject != nil {
+		subject = manifest.Subject.Digest
+	}
+
+	mediaTypes := []string{manifest.ArtifactType, manifest.Config.MediaType}
+	for _, layer := range manifest.Layers {
+		mediaTypes = append(mediaTypes, layer.MediaType)
+	}
+	for _, mediaType := range mediaTypes {
+		if kind, ok := artifactMediaTypes[mediaType]; ok {
+			return kind, subject
+		}
+	}
+
+	if manifest.ArtifactType != "" || !isImageConfig(manifest.Config.MediaType) {
+		return ArtifactOther, subject
+	}
+
+	return "", subject
+}
+
+func isImageConfig(mediaType string) bool {
+	return mediaType == v1.MediaTypeImageConfig || mediaType == schema2.MediaTypeImageConfig
+}
@@ -0,0 +1,76 @@
+package registry
+
+import (
+	"testing"
+
+	digest "github.com/opencontainers/go-digest"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+func TestParseArtifactTag(t *testing.T) {
+
+	subject := digest.FromString("image")
+	tests := []struct {
+		tag  string
+		kind string
+		ok   bool
+	}{
+		{"sha256-" + subject.Encoded() + ".sig", ArtifactSignature, true},
+		{"sha256-" + subject.Encoded() + ".att", ArtifactAttestation, true},
+		{"sha256-" + subject.Encoded() + ".sbom", ArtifactSBOM, true},
+		{"sha256-" + subject.Encoded() + ".txt", "", false},
+		{"sha256-abc.sig", "", false},
+		{"1.0.sig", "", false},
+		{"latest", "", false},
+	}
+
+	for _, test := range tests {
+		kind, got, ok := ParseArtifactTag(test.tag)
+		if kind != test.kind || ok != test.ok || (ok && got != subject) {
+			t.Errorf("ParseArtifactTag(%q) = %q, %q, %v, want %q, %v", test.tag, kind, got, ok, test.kind, test.ok)
+		}
+	}
+}
+
+func TestManifestArtifact(t *testing.T) {
+
+	subject := digest.FromString("image")
+	tests := []struct {
+		name     string
+		manifest Manifest
+		kind     string
+		subject  digest.Digest
+	}{
+		{
+			name:     "image",
+			manifest: imageManifest(`{"schemaVersion": 2, "config": {"mediaType": "` + v1.MediaTypeImageConfig + `"}}`),
+		},
+		{
+			name:     "cosign signature",
+			manifest: imageManifest(`{"schemaVersion": 2, "config": {"mediaType": "` + v1.MediaTypeImageConfig + `"}, "layers": [{"mediaType": "application/vnd.dev.cosign.simplesigning.v1+json"}]}`),
+			kind:     ArtifactSignature,
+		},
+		{
+			name:     "referrer SBOM",
+			manifest: imageManifest(`{"schemaVersion": 2, "artifactType": "application/spdx+json", "subject": {"digest": "` + subject.String() + `"}}`),
+			kind:     ArtifactSBOM,
+			subject:  subject,
+		},
+		{
+			name:     "unknown artifact",
+			manifest: imageManifest(`{"schemaVersion": 2, "config": {"mediaType": "application/vnd.example.config+json"}}`),
+			kind:     ArtifactOther,
+		},
+	}
+
+	for _, test := range tests {
+		kind, got := test.manifest.artifact()
+		if kind != test.kind || got != test.subject {
+			t.Errorf("%s: artifact() = %q, %q, want %q, %q", test.name, kind, got, test.kind, test.subject)
+		}
+	}
+}
+
+func imageManifest(content string) Manifest {
+	return Manifest{MediaType: v1.MediaTypeImageManifest, Content: []byte(content)}
+}
@@ -0,0 +1,202 @@
+package registry
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/docker/distribution/manifest/schema2"
+	digest "github.com/opencontainers/go-digest"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// GetBlob opens the content of a blob. The caller is responsible for closing
+// the returned reader and for verifying the content against the digest.
+func (rc *registryClient) GetBlob(hostname string, repo string, dgst digest.Digest) (io.ReadCloser, int64, error) {
+
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("https://%s/v2/%s/blobs/%s", hostname, repo, dgst), nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	resp, err := rc.do(hostname, req)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return resp.Body, resp.ContentLength, nil
+}
+
+// IsNonDistributable reports whether a layer is served from its own URLs
+// rather than the registry, such as windows base layers.
+func IsNonDistributable(desc v1.Descriptor) bool {
+	switch desc.MediaType {
+	case schema2.MediaTypeForeignLayer, v1.MediaTypeImageLayerNonDistributable, v1.MediaTypeImageLayerNonDistributableGzip:
+		return true
+	}
+	return false
+}
+
+// BlobExists reports whether the repository already has the blob.
+func (rc *registryClient) BlobExists(hostname string, repo string, dgst digest.Digest) (bool, error) {
+
+	req, err := http.NewRequest(http.MethodHead, fmt.Sprintf("https://%s/v2/%s/blobs/%s", hostname, repo, dgst), nil)
+	if err != nil {
+		return false, err
+	}
+
+	resp, err := rc.do(hostname, req)
+	if IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+
+	return true, nil
+}
+
+// MountBlob asks the registry to link a blob from another repository on the
+// same registry. It reports false when the registry declined to mount it.
+func (rc *registryClient) MountBlob(hostname string, repo string, fromRepo string, dgst digest.Digest) (bool, error) {
+
+	query := url.Values{}
+	query.Set("mount", dgst.String())
+	query.Set("from", fromRepo)
+
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("https://%s/v2/%s/blobs/uploads/?%s", hostname, repo, query.Encode()), nil)
+	if err != nil {
+		return false, err
+	}
+
+	resp, err := rc.do(hostname, req)
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode == http.StatusCreated {
+		return true, nil
+	}
+
+	// the registry opened a regular upload session instead, which is not needed
+	if location, err := uploadLocation(resp); err == nil {
+		if req, err := http.NewRequest(http.MethodDelete, location, nil); err == nil {
+			if resp, err := rc.do(hostname, req); err == nil {
+				resp.Body.Close()
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// UploadBlob uploads the content of a blob. With a chunkSize of zero or less
+// the content is sent in a single request, otherwise it is sent in chunks of
+// chunkSize bytes.
+func (rc *registryClient) UploadBlob(hostname string, repo string, desc v1.Descriptor, content io.Reader, chunkSize int64) error {
+
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("https://%s/v2/%s/blobs/uploads/", hostname, repo), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := rc.do(hostname, req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	location, err := uploadLocation(resp)
+	if err != nil {
+		return err
+	}
+
+	if chunkSize > 0 {
+		location, err = rc.uploadChunks(hostname, location, content, chunkSize)
+		if err != nil {
+			return err
+		}
+		content = nil
+	}
+
+	completeURL, err := url.Parse(location)
+	if err != nil {
+		return err
+	}
+	query := completeURL.Query()
+	query.Set("digest", desc.Digest.String())
+	completeURL.RawQuery = query.Encode()
+
+	req, err = http.NewRequest(http.MethodPut, completeURL.String(), content)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	if content != nil {
+		req.ContentLength = desc.Size
+	}
+
+	resp, err = rc.do(hostname, req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+// uploadChunks sends content with PATCH requests and returns the location to complete the upload at.
+func (rc *registryClient) uploadChunks(hostname string, location string, content io.Reader, chunkSize int64) (string, error) {
+
+	chunk := make([]byte, chunkSize)
+	offset := int64(0)
+
+	for {
+		n, err := io.ReadFull(content, chunk)
+		if err == io.EOF {
+			return location, nil
+		}
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return "", err
+		}
+
+		req, err := http.NewRequest(http.MethodPatch, location, bytes.NewReader(chunk[:n]))
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Content-Type", "application/octet-stream")
+		req.Header.Set("Content-Range", fmt.Sprintf("%d-%d", offset, offset+int64(n)-1))
+
+		resp, err := rc.do(hostname, req)
+		if err != nil {
+			return "", err
+		}
+		resp.Body.Close()
+
+		location, err = uploadLocation(resp)
+		if err != nil {
+			return "", err
+		}
+		offset += int64(n)
+
+		if int64(n) < chunkSize {
+			return location, nil
+		}
+	}
+}
+
+// uploadLocation resolves the Location header of an upload response, which
+// registries are allowed to send as a relative URL.
+func uploadLocation(resp *http.Response) (string, error) {
+
+	location, err := resp.Location()
+	if err != nil {
+		return "", fmt.Errorf("registry did not return an upload location: %v", err)
+	}
+
+	return location.String(), nil
+}
@@ -2,15 +2,20 @@ package registry
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
-	"strings"
+	"os"
+	"sort"
 
 	"github.com/docker/distribution/manifest/schema1"
+	digest "github.com/opencontainers/go-digest"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
 	auth "github.com/zawachte-msft/bupkis/pkg/auth/docker"
 
-	//"strings"
+	"strings"
 	"time"
 )
 
@@ -41,10 +46,39 @@ type V1Compatibility struct {
 
 // ImageData represents image object
 type ImageData struct {
+	Name      string
+	Created   time.Time
+	Tag       string
+	Hostname  string
+	Digest    digest.Digest
+	MediaType string
+	// Size is the sum of Blobs, the space the tag would take up on its own
+	Size int64
+	// Layers are the layers of the image, base layer first
+	Layers []v1.Descriptor
+	// Blobs are the manifests, configs and layers the tag references
+	Blobs []v1.Descriptor
+	// ArtifactKind is set for signatures, attestations, SBOMs and other
+	// artifacts, which have no layers or creation time of their own
+	ArtifactKind string
+	// Subject is the digest of the image an artifact refers to
+	Subject digest.Digest
+}
+
+// IsArtifact reports whether the tag holds an artifact rather than an image
+func (image ImageData) IsArtifact() bool {
+	return image.ArtifactKind != ""
+}
+
+// RepoData is a repository and its tags, listed without fetching any manifests
+type RepoData struct {
 	Name     string
-	Created  time.Time
-	Tag      string
 	Hostname string
+	Tags     []string
+	// PushedAt and PullCount are known when a registry adapter listed the
+	// repository, see Repository
+	PushedAt  time.Time
+	PullCount int64
 }
 
 // AllImages is used to get all the images
@@ -52,21 +86,87 @@ type AllImages struct {
 	Images []ImageData
 }
 
+// Client provides read and write operations against container registries.
+type Client interface {
+	GetImageDataList(hostname string, repo string) ([]ImageData, error)
+	GetImageData(hostname string, repo string, tag string) (ImageData, error)
+	GetTags(hostname string, repo string) ([]string, error)
+	GetRepos() ([]ImageData, error)
+	GetReposByHostName(hostname string) ([]ImageData, error)
+	GetRepoList() ([]RepoData, error)
+	GetRepoListByHostName(hostname string) ([]RepoData, error)
+	GetCatalog(hostname string) ([]string, error)
+	GetHostnames() []string
+	Ping(hostname string) error
+	GetManifest(hostname string, repo string, reference string) (Manifest, error)
+	PutManifest(hostname string, repo string, reference string, manifest Manifest) (digest.Digest, error)
+	GetManifestDigest(hostname string, repo string, reference string) (digest.Digest, error)
+	DeleteManifest(hostname string, repo string, dgst digest.Digest) error
+	GetBlob(hostname string, repo string, dgst digest.Digest) (io.ReadCloser, int64, error)
+	BlobExists(hostname string, repo string, dgst digest.Digest) (bool, error)
+	MountBlob(hostname string, repo string, fromRepo string, dgst digest.Digest) (bool, error)
+	UploadBlob(hostname string, repo string, desc v1.Descriptor, content io.Reader, chunkSize int64) error
+}
+
+var _ Client = &registryClient{}
+
 type RegistryClientOptions struct {
 	Hostname string
+	// RepoFilter and TagFilter select the repositories and tags to list
+	// before any manifests are fetched. Nil selects everything.
+	RepoFilter func(repo string) bool
+	TagFilter  func(tag string) bool
+	// PullSecrets are Kubernetes image pull secrets to read credentials from
+	PullSecrets []string
+	// CredentialProviderConfig lists credential provider binaries to get credentials from
+	CredentialProviderConfig string
+	// Repository is set when the client only works on one repository of
+	// Hostname, so that credential providers can scope their credential to it
+	Repository string
+	// TagMetadata lets GetImageDataList describe the tags of an Azure
+	// Container Registry from its metadata API instead of their manifests.
+	// The images then have no Layers or Blobs.
+	TagMetadata bool
+	// AllPlatforms makes GetImageData read the manifest of every platform of
+	// an index, so that Blobs and Size count them all. Otherwise only the
+	// platform the layers and creation time come from is read.
+	AllPlatforms bool
+	// ProbeRegistries lets the adapters send a request to registries without
+	// a well-known hostname, to detect vendors such as Harbor that run anywhere
+	ProbeRegistries bool
 }
 
 type registryClient struct {
 	hostname      string
 	httpClientMap map[string]*http.Client
+	repoFilter    func(repo string) bool
+	tagFilter     func(tag string) bool
+	tagMetadata   bool
+	allPlatforms  bool
+	// probeRegistries is passed to the adapters as AdapterRequest.Probe
+	probeRegistries bool
+	// credentials are given to the adapters, whose APIs authenticate differently
+	credentials map[string]credential
+	// adapters are the detected adapters of the registries, nil for none
+	adapters map[string]Adapter
+}
+
+type credential struct {
+	username string
+	password string
 }
 
 func New(options RegistryClientOptions) (*registryClient, error) {
 
 	httpClientMap := make(map[string]*http.Client)
+	credentials := make(map[string]credential)
 
 	// Prepare auth client
-	cli, err := auth.NewClient()
+	cli, err := auth.NewClientWithOptions(auth.ClientOptions{
+		PullSecrets:              options.PullSecrets,
+		CredentialProviderConfig: options.CredentialProviderConfig,
+		Repository:               options.Repository,
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -77,66 +177,96 @@ func New(options RegistryClientOptions) (*registryClient, error) {
 			return nil, err
 		}
 
-		basicAuthTransport := &BasicTransport{
-			Transport: http.DefaultTransport,
-			URL:       options.Hostname,
-			Username:  username,
-			Password:  password,
-		}
-		errorTransport := &ErrorTransport{
-			Transport: basicAuthTransport,
-		}
-
-		httpClientMap[options.Hostname] = &http.Client{
-			Transport: errorTransport,
-		}
+		httpClientMap[options.Hostname] = newHTTPClient(options.Hostname, username, password)
+		credentials[options.Hostname] = credential{username: username, password: password}
 	} else {
 		authConfigMap, err := cli.GetAllCredentials()
 		if err != nil {
 			return nil, err
 		}
 
-		for hostname, authConfig := range authConfigMap {
-			basicAuthTransport := &BasicTransport{
-				Transport: http.DefaultTransport,
-				URL:       hostname,
-				Username:  authConfig.Username,
-				Password:  authConfig.Password,
-			}
-			errorTransport := &ErrorTransport{
-				Transport: basicAuthTransport,
+		for key, authConfig := range authConfigMap {
+			hostname := RegistryHostname(key)
+			username, password := authConfig.Username, authConfig.Password
+			// identity tokens are refresh tokens, exchanged without a username
+			if authConfig.IdentityToken != "" {
+				username, password = "", authConfig.IdentityToken
 			}
 
-			httpClientMap[hostname] = &http.Client{
-				Transport: errorTransport,
-			}
+			httpClientMap[hostname] = newHTTPClient(hostname, username, password)
+			credentials[hostname] = credential{username: username, password: password}
 		}
 	}
 
 	return &registryClient{
-		hostname:      options.Hostname,
-		httpClientMap: httpClientMap,
+		hostname:        options.Hostname,
+		httpClientMap:   httpClientMap,
+		repoFilter:      options.RepoFilter,
+		tagFilter:       options.TagFilter,
+		tagMetadata:     options.TagMetadata,
+		allPlatforms:    options.AllPlatforms,
+		probeRegistries: options.ProbeRegistries,
+		credentials:     credentials,
+		adapters:        map[string]Adapter{},
 	}, nil
 }
 
-func (rc *registryClient) GetImageDataList(hostname string, repo string) ([]ImageData, error) {
+// RegistryHostname turns the key of a credential into the hostname of its
+// registry, as docker keeps the Docker Hub credential under https://index.docker.io/v1/
+func RegistryHostname(key string) string {
+	hostname := strings.TrimPrefix(strings.TrimPrefix(key, "https://"), "http://")
+	hostname = strings.SplitN(hostname, "/", 2)[0]
+	if isDockerHub(hostname) {
+		return dockerHubHostname
+	}
+	return hostname
+}
 
-	bodyText, err := rc.requestAndGetBody(hostname, fmt.Sprintf("https://%s/v2/%s/tags/list", hostname, repo))
-	if err != nil {
-		return nil, err
+// newHTTPClient returns a client that authenticates to the registry with the
+// credential and turns error responses into HTTPStatusError.
+func newHTTPClient(hostname string, username string, password string) *http.Client {
+
+	if isDockerHub(hostname) {
+		hostname = dockerHubRegistry
 	}
 
-	tagsResp := tagsResponse{}
+	return &http.Client{
+		Transport: &ErrorTransport{
+			Transport: &dockerHubTransport{
+				Transport: &TokenTransport{
+					Transport: http.DefaultTransport,
+					URL:       hostname,
+					Username:  username,
+					Password:  password,
+				},
+			},
+		},
+	}
+}
 
-	err = json.Unmarshal(bodyText, &tagsResp)
+func (rc *registryClient) GetImageDataList(hostname string, repo string) ([]ImageData, error) {
+
+	if rc.tagMetadata && IsACR(hostname) {
+		images, err := rc.getACRImageDataList(hostname, repo)
+		if err == nil || !(IsNotFound(err) || IsUnauthorized(err) || IsForbidden(err)) {
+			return images, err
+		}
+		// the metadata API needs the metadata_read permission, the manifests do not
+	}
+
+	tags, err := rc.GetTags(hostname, repo)
 	if err != nil {
 		return nil, err
 	}
 
 	returnImageData := []ImageData{}
-	for _, tag := range tagsResp.Tags {
+	for _, tag := range tags {
+
+		if rc.tagFilter != nil && !rc.tagFilter(tag) {
+			continue
+		}
 
-		imageData, err := rc.GetImageData(hostname, tagsResp.Name, tag)
+		imageData, err := rc.GetImageData(hostname, repo, tag)
 		if err != nil {
 			return nil, err
 		}
@@ -147,33 +277,228 @@ func (rc *registryClient) GetImageDataList(hostname string, repo string) ([]Imag
 	return returnImageData, nil
 
 }
+
+// GetTags lists the tags of a repository without fetching any manifests.
+func (rc *registryClient) GetTags(hostname string, repo string) ([]string, error) {
+
+	bodyText, err := rc.requestAndGetBody(hostname, fmt.Sprintf("https://%s/v2/%s/tags/list", hostname, repo))
+	if err != nil {
+		return nil, err
+	}
+
+	tagsResp := tagsResponse{}
+
+	err = json.Unmarshal(bodyText, &tagsResp)
+	if err != nil {
+		return nil, err
+	}
+
+	return tagsResp.Tags, nil
+}
+
 func (rc *registryClient) GetImageData(hostname string, repo string, tag string) (ImageData, error) {
-	bodyText1, err := rc.requestAndGetBody(hostname, fmt.Sprintf("https://%s/v2/%s/manifests/%s", hostname, repo, tag))
+	manifest, err := rc.GetManifest(hostname, repo, tag)
 	if err != nil {
 		return ImageData{}, err
 	}
 
-	mani := schema1.Manifest{}
+	imageData := ImageData{
+		Name:      repo,
+		Tag:       tag,
+		Hostname:  hostname,
+		Digest:    manifest.Digest,
+		MediaType: manifest.MediaType,
+	}
 
-	err = json.Unmarshal(bodyText1, &mani)
+	imageData.ArtifactKind, imageData.Subject = manifest.artifact()
+	if kind, subject, ok := ParseArtifactTag(tag); ok {
+		if imageData.ArtifactKind == "" || imageData.ArtifactKind == ArtifactOther {
+			imageData.ArtifactKind = kind
+		}
+		if imageData.Subject == "" {
+			imageData.Subject = subject
+		}
+	}
+
+	if manifest.IsSchema1() {
+		err = rc.describeSchema1Image(&imageData, manifest)
+	} else if imageData.IsArtifact() {
+		err = describeArtifact(&imageData, manifest)
+	} else {
+		err = rc.describeImage(&imageData, manifest)
+	}
 	if err != nil {
 		return ImageData{}, err
 	}
 
+	for _, blob := range imageData.Blobs {
+		imageData.Size += blob.Size
+	}
+
+	return imageData, nil
+
+}
+
+func (rc *registryClient) describeSchema1Image(imageData *ImageData, manifest Manifest) error {
+
+	mani := schema1.Manifest{}
+
+	err := json.Unmarshal(manifest.Content, &mani)
+	if err != nil {
+		return err
+	}
+
+	if len(mani.History) == 0 {
+		return fmt.Errorf("manifest %s has no history", manifest.Digest)
+	}
+
 	v1Compatibility := V1Compatibility{}
 
 	err = json.Unmarshal([]byte(mani.History[0].V1Compatibility), &v1Compatibility)
 	if err != nil {
-		return ImageData{}, err
+		return err
 	}
 
-	return ImageData{
-		Name:     repo,
-		Tag:      tag,
-		Created:  v1Compatibility.Created,
-		Hostname: hostname,
-	}, nil
+	imageData.Created = v1Compatibility.Created
+	imageData.Blobs = append(imageData.Blobs, manifest.Descriptor())
+
+	// schema1 lists the newest layer first and does not record sizes
+	for i := len(mani.FSLayers) - 1; i >= 0; i-- {
+		layer := v1.Descriptor{Digest: mani.FSLayers[i].BlobSum}
+		imageData.Layers = append(imageData.Layers, layer)
+		imageData.Blobs = append(imageData.Blobs, layer)
+	}
+
+	return nil
+}
+
+// describeArtifact only records the blobs of an artifact, as its config is
+// not an image config and its layers are not filesystem layers.
+func describeArtifact(imageData *ImageData, manifest Manifest) error {
+
+	descriptors, err := manifest.References()
+	if err != nil {
+		return err
+	}
+
+	imageData.Blobs = append(imageData.Blobs, manifest.Descriptor())
+	imageData.Blobs = append(imageData.Blobs, descriptors...)
+
+	return nil
+}
+
+// describeImage fills in the layers, blobs and creation time of an image or
+// index. The layers and creation time of an index come from the linux/amd64
+// image when there is one, and with AllPlatforms every platform counts
+// towards the blobs.
+func (rc *registryClient) describeImage(imageData *ImageData, manifest Manifest) error {
+
+	imageData.Blobs = append(imageData.Blobs, manifest.Descriptor())
+
+	images := []Manifest{manifest}
+	platformImage := 0
+
+	if manifest.IsIndex() {
+		children, err := manifest.References()
+		if err != nil {
+			return err
+		}
+
+		if !rc.allPlatforms {
+			children = platformChildren(children)
+		}
+
+		images = []Manifest{}
+		platformImage = -1
+		for _, child := range children {
+			childManifest, err := rc.GetManifest(imageData.Hostname, imageData.Name, child.Digest.String())
+			if err != nil {
+				return err
+			}
+			imageData.Blobs = append(imageData.Blobs, childManifest.Descriptor())
+
+			if childManifest.IsIndex() || childManifest.IsSchema1() {
+				continue
+			}
+			if platformImage == -1 || (child.Platform != nil && child.Platform.OS == "linux" && child.Platform.Architecture == "amd64") {
+				platformImage = len(images)
+			}
+			images = append(images, childManifest)
+		}
+
+		if platformImage == -1 {
+			return nil
+		}
+	}
+
+	for i, image := range images {
+		descriptors, err := image.References()
+		if err != nil {
+			return err
+		}
+		if len(descriptors) == 0 {
+			continue
+		}
+		imageData.Blobs = append(imageData.Blobs, descriptors...)
+
+		if i != platformImage {
+			continue
+		}
+
+		imageData.Layers = descriptors[1:]
+		imageData.Created, err = rc.getImageCreated(imageData.Hostname, imageData.Name, descriptors[0])
+		if err != nil {
+			return err
+		}
+	}
 
+	return nil
+}
+
+// platformChildren picks the child of an index to describe it by, the
+// linux/amd64 image or else the first child with a known platform.
+func platformChildren(children []v1.Descriptor) []v1.Descriptor {
+
+	found := -1
+	for i, child := range children {
+		if child.Platform == nil {
+			if found == -1 {
+				found = i
+			}
+			continue
+		}
+		if child.Platform.OS == "linux" && child.Platform.Architecture == "amd64" {
+			return children[i : i+1]
+		}
+		if found == -1 && child.Platform.OS != "unknown" {
+			found = i
+		}
+	}
+
+	if found == -1 {
+		return nil
+	}
+	return children[found : found+1]
+}
+
+// getImageCreated reads the creation time from an image config blob
+func (rc *registryClient) getImageCreated(hostname string, repo string, config v1.Descriptor) (time.Time, error) {
+
+	blob, _, err := rc.GetBlob(hostname, repo, config.Digest)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer blob.Close()
+
+	imageConfig := struct {
+		Created time.Time `json:"created"`
+	}{}
+
+	if err := json.NewDecoder(blob).Decode(&imageConfig); err != nil {
+		return time.Time{}, err
+	}
+
+	return imageConfig.Created, nil
 }
 
 func (rc *registryClient) GetRepos() ([]ImageData, error) {
@@ -195,21 +520,20 @@ func (rc *registryClient) GetRepos() ([]ImageData, error) {
 func (rc *registryClient) GetReposByHostName(hostname string) ([]ImageData, error) {
 
 	returnImageData := []ImageData{}
-	bodyText, err := rc.requestAndGetBody(hostname, fmt.Sprintf("https://%s/v2/_catalog", hostname))
+	repos, err := rc.GetCatalog(hostname)
 	if err != nil {
 		return nil, err
 	}
 
-	repoResp := repositoriesResponse{}
-	err = json.Unmarshal(bodyText, &repoResp)
-	if err != nil {
-		return nil, err
-	}
+	for _, repo := range repos {
 
-	for _, repo := range repoResp.Repositories {
+		if rc.repoFilter != nil && !rc.repoFilter(repo) {
+			continue
+		}
 
 		images, err := rc.GetImageDataList(hostname, repo)
 		if err != nil {
+			fmt.Fprintf(os.Stderr, "WARNING: skipping %s/%s: %v\n", hostname, repo, err)
 			continue
 		}
 
@@ -219,9 +543,124 @@ func (rc *registryClient) GetReposByHostName(hostname string) ([]ImageData, erro
 	return returnImageData, nil
 }
 
+// GetRepoList lists the repositories and tags of every registry without fetching any manifests.
+func (rc *registryClient) GetRepoList() ([]RepoData, error) {
+
+	returnRepoData := []RepoData{}
+
+	for _, hostname := range rc.GetHostnames() {
+		repoData, err := rc.GetRepoListByHostName(hostname)
+		if err != nil {
+			return nil, err
+		}
+
+		returnRepoData = append(returnRepoData, repoData...)
+	}
+
+	return returnRepoData, nil
+}
+
+// GetRepoListByHostName lists the repositories and tags of a registry without fetching any manifests.
+func (rc *registryClient) GetRepoListByHostName(hostname string) ([]RepoData, error) {
+
+	returnRepoData := []RepoData{}
+	repos, err := rc.repositories(hostname)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, repo := range repos {
+
+		if rc.repoFilter != nil && !rc.repoFilter(repo.Name) {
+			continue
+		}
+
+		tags, err := rc.GetTags(hostname, repo.Name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "WARNING: skipping %s/%s: %v\n", hostname, repo.Name, err)
+			continue
+		}
+
+		repoData := RepoData{
+			Name:      repo.Name,
+			Hostname:  hostname,
+			Tags:      []string{},
+			PushedAt:  repo.PushedAt,
+			PullCount: repo.PullCount,
+		}
+		for _, tag := range tags {
+			if rc.tagFilter != nil && !rc.tagFilter(tag) {
+				continue
+			}
+			repoData.Tags = append(repoData.Tags, tag)
+		}
+
+		returnRepoData = append(returnRepoData, repoData)
+	}
+
+	return returnRepoData, nil
+}
+
+// GetCatalog lists the repositories of a registry without fetching any tags
+// or manifests, through the API of its vendor when an adapter serves it.
+func (rc *registryClient) GetCatalog(hostname string) ([]string, error) {
+
+	repos, err := rc.repositories(hostname)
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, repo := range repos {
+		names = append(names, repo.Name)
+	}
+
+	return names, nil
+}
+
+// catalog lists the repositories of a registry through the distribution API
+func (rc *registryClient) catalog(hostname string) ([]string, error) {
+
+	bodyText, err := rc.requestAndGetBody(hostname, fmt.Sprintf("https://%s/v2/_catalog", hostname))
+	if err != nil {
+		return nil, err
+	}
+
+	repoResp := repositoriesResponse{}
+	err = json.Unmarshal(bodyText, &repoResp)
+	if err != nil {
+		return nil, err
+	}
+
+	return repoResp.Repositories, nil
+}
+
+// GetHostnames lists the registries the client has credentials for, sorted by name.
+func (rc *registryClient) GetHostnames() []string {
+
+	hostnames := []string{}
+	for hostname := range rc.httpClientMap {
+		hostnames = append(hostnames, hostname)
+	}
+	sort.Strings(hostnames)
+
+	return hostnames
+}
+
+// Ping checks the API version endpoint, which only succeeds when the registry accepts the credential.
+func (rc *registryClient) Ping(hostname string) error {
+	_, err := rc.requestAndGetBody(hostname, fmt.Sprintf("https://%s/v2/", hostname))
+	return err
+}
+
 func (rc *registryClient) requestAndGetBody(hostname string, query string) ([]byte, error) {
 
-	resp, err := rc.httpClientMap[hostname].Get(query)
+	req, err := http.NewRequest(http.MethodGet, query, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := rc.do(hostname, req)
 	if err != nil {
 		return nil, err
 	}
@@ -235,22 +674,24 @@ func (rc *registryClient) requestAndGetBody(hostname string, query string) ([]by
 	return bodyText, nil
 }
 
-type BasicTransport struct {
-	Transport http.RoundTripper
-	URL       string
-	Username  string
-	Password  string
+func (rc *registryClient) do(hostname string, req *http.Request) (*http.Response, error) {
+
+	httpClient, err := rc.httpClient(hostname)
+	if err != nil {
+		return nil, err
+	}
+
+	return httpClient.Do(req)
 }
 
-func (t *BasicTransport) RoundTrip(req *http.Request) (*http.Response, error) {
-	if strings.Contains(req.URL.String(), t.URL) {
-		if t.Username != "" || t.Password != "" {
-			req.SetBasicAuth(t.Username, t.Password)
-		}
+func (rc *registryClient) httpClient(hostname string) (*http.Client, error) {
+
+	httpClient, ok := rc.httpClientMap[hostname]
+	if !ok {
+		return nil, fmt.Errorf("no client configured for registry %s", hostname)
 	}
 
-	resp, err := t.Transport.RoundTrip(req)
-	return resp, err
+	return httpClient, nil
 }
 
 type HTTPStatusError struct {
@@ -258,14 +699,45 @@ type HTTPStatusError struct {
 	// Copied from `Response.Body` to avoid problems with unclosed bodies later.
 	// Nobody calls `err.Response.Body.Close()`, ever.
 	Body []byte
+	// Cause is why the request could not be authenticated, such as a failed
+	// token exchange, when the registry answered 401
+	Cause error
 }
 
 func (err *HTTPStatusError) Error() string {
+	if err.Cause != nil {
+		return fmt.Sprintf("http: non-successful response (status=%v body=%q): %v", err.Response.StatusCode, err.Body, err.Cause)
+	}
 	return fmt.Sprintf("http: non-successful response (status=%v body=%q)", err.Response.StatusCode, err.Body)
 }
 
+func (err *HTTPStatusError) Unwrap() error {
+	return err.Cause
+}
+
 var _ error = &HTTPStatusError{}
 
+// IsNotFound reports whether err is a 404 response, such as for a repository that does not exist yet
+func IsNotFound(err error) bool {
+	return isStatus(err, http.StatusNotFound)
+}
+
+// IsUnauthorized reports whether err is a 401 response, for a missing or rejected credential
+func IsUnauthorized(err error) bool {
+	return isStatus(err, http.StatusUnauthorized)
+}
+
+// IsForbidden reports whether err is a 403 response, for a credential without access
+func IsForbidden(err error) bool {
+	return isStatus(err, http.StatusForbidden)
+}
+
+// isStatus reports whether err is an HTTPStatusError with the given status code
+func isStatus(err error, statusCode int) bool {
+	statusErr := &HTTPStatusError{}
+	return errors.As(err, &statusErr) && statusErr.Response.StatusCode == statusCode
+}
+
 type ErrorTransport struct {
 	Transport http.RoundTripper
 }
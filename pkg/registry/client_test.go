@@ -0,0 +1,95 @@
+package registry
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testHostname is the registry served by the test server
+const testHostname = "registry.example.com"
+
+// newTestTransport starts a TLS server for handler and returns a transport
+// that sends the requests for every host to it.
+func newTestTransport(t *testing.T, handler http.Handler) http.RoundTripper {
+
+	server := httptest.NewTLSServer(handler)
+	t.Cleanup(server.Close)
+
+	transport := server.Client().Transport.(*http.Transport).Clone()
+	transport.DialContext = func(ctx context.Context, network string, addr string) (net.Conn, error) {
+		return (&net.Dialer{}).DialContext(ctx, network, server.Listener.Addr().String())
+	}
+	// the certificate of the test server is issued for example.com
+	transport.TLSClientConfig.ServerName = "example.com"
+
+	return transport
+}
+
+// newTestClient returns a client of testHostname, served by handler, without a credential
+func newTestClient(t *testing.T, handler http.Handler) *registryClient {
+	return &registryClient{
+		hostname: testHostname,
+		httpClientMap: map[string]*http.Client{
+			testHostname: {
+				Transport: &ErrorTransport{
+					Transport: &TokenTransport{
+						Transport: newTestTransport(t, handler),
+						URL:       testHostname,
+					},
+				},
+			},
+		},
+		credentials: map[string]credential{},
+		adapters:    map[string]Adapter{},
+	}
+}
+
+func TestGetRepoListByHostName(t *testing.T) {
+
+	tags := map[string][]string{
+		"team/app": {"1.0", "2.0", "sha256-abc.sig"},
+		"team/web": {"1.0"},
+		"other":    {"1.0"},
+	}
+
+	rc := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v2/_catalog":
+			writeJSON(w, map[string][]string{"repositories": {"team/app", "team/broken", "team/web", "other"}})
+		case strings.HasSuffix(r.URL.Path, "/tags/list"):
+			name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v2/"), "/tags/list")
+			if name == "other" {
+				t.Errorf("tags of %s listed, though the repository filter leaves it out", name)
+			}
+			if _, ok := tags[name]; !ok {
+				http.Error(w, `{"errors":[{"code":"NAME_UNKNOWN"}]}`, http.StatusNotFound)
+				return
+			}
+			writeJSON(w, map[string]interface{}{"name": name, "tags": tags[name]})
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+			http.NotFound(w, r)
+		}
+	}))
+	rc.repoFilter = func(repo string) bool { return strings.HasPrefix(repo, "team/") }
+	rc.tagFilter = func(tag string) bool { return !strings.HasSuffix(tag, ".sig") }
+
+	repos, err := rc.GetRepoListByHostName(testHostname)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the repository whose tags cannot be listed is skipped
+	want := []RepoData{
+		{Name: "team/app", Hostname: testHostname, Tags: []string{"1.0", "2.0"}, PullCount: -1},
+		{Name: "team/web", Hostname: testHostname, Tags: []string{"1.0"}, PullCount: -1},
+	}
+	if !reflect.DeepEqual(repos, want) {
+		t.Errorf("GetRepoListByHostName() = %+v, want %+v", repos, want)
+	}
+}
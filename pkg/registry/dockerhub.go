@@ -0,0 +1,166 @@
+package registry
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	// dockerHubHostname is how Docker Hub is named in image references
+	dockerHubHostname = "docker.io"
+	// dockerHubRegistry serves the distribution API of Docker Hub
+	dockerHubRegistry = "registry-1.docker.io"
+	// dockerHubAPI serves the repository listings of Docker Hub
+	dockerHubAPI = "https://hub.docker.com"
+)
+
+type dockerHubLoginResponse struct {
+	Token string `json:"token"`
+}
+
+type dockerHubPage struct {
+	Next    string            `json:"next"`
+	Results []json.RawMessage `json:"results"`
+}
+
+type dockerHubOrg struct {
+	Name string `json:"orgname"`
+}
+
+type dockerHubRepository struct {
+	Name        string    `json:"name"`
+	Namespace   string    `json:"namespace"`
+	PullCount   int64     `json:"pull_count"`
+	LastUpdated time.Time `json:"last_updated"`
+}
+
+// isDockerHub reports whether the hostname names Docker Hub
+func isDockerHub(hostname string) bool {
+	switch hostname {
+	case dockerHubHostname, "index.docker.io", dockerHubRegistry:
+		return true
+	}
+	return false
+}
+
+// dockerHubTransport sends the requests for the names of Docker Hub used in
+// image references and credentials to the host serving its registry.
+type dockerHubTransport struct {
+	Transport http.RoundTripper
+}
+
+func (t *dockerHubTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	if !isDockerHub(req.URL.Host) || req.URL.Host == dockerHubRegistry {
+		return t.Transport.RoundTrip(req)
+	}
+
+	registryReq := req.Clone(req.Context())
+	registryReq.URL.Host = dockerHubRegistry
+	registryReq.Host = ""
+
+	return t.Transport.RoundTrip(registryReq)
+}
+
+// dockerHubAdapter lists the repositories of the user and of their
+// organizations, as Docker Hub has no catalog.
+type dockerHubAdapter struct{}
+
+func (a *dockerHubAdapter) Name() string {
+	return "Docker Hub"
+}
+
+func (a *dockerHubAdapter) Detect(req AdapterRequest) bool {
+	return isDockerHub(req.Hostname)
+}
+
+func (a *dockerHubAdapter) Repositories(req AdapterRequest) ([]Repository, error) {
+
+	if req.Username == "" || req.Password == "" {
+		return nil, ErrNotSupported
+	}
+
+	token, err := a.login(req)
+	if err != nil {
+		return nil, err
+	}
+	header := http.Header{}
+	header.Set("Authorization", "JWT "+token)
+
+	namespaces := []string{req.Username}
+	err = a.getPages(req, dockerHubAPI+"/v2/user/orgs/?page_size=100", header, func(result json.RawMessage) error {
+		org := dockerHubOrg{}
+		if err := json.Unmarshal(result, &org); err != nil {
+			return err
+		}
+		namespaces = append(namespaces, org.Name)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	repos := []Repository{}
+	for _, namespace := range namespaces {
+		err := a.getPages(req, fmt.Sprintf("%s/v2/repositories/%s/?page_size=100", dockerHubAPI, namespace), header, func(result json.RawMessage) error {
+			repo := dockerHubRepository{}
+			if err := json.Unmarshal(result, &repo); err != nil {
+				return err
+			}
+			repos = append(repos, Repository{
+				Name:      repo.Namespace + "/" + repo.Name,
+				PushedAt:  repo.LastUpdated,
+				PullCount: repo.PullCount,
+			})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return repos, nil
+}
+
+// login exchanges the credential for a token of the Docker Hub API
+func (a *dockerHubAdapter) login(req AdapterRequest) (string, error) {
+
+	body, err := json.Marshal(map[string]string{"username": req.Username, "password": req.Password})
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := req.Client.Post(dockerHubAPI+"/v2/users/login/", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	loginResp := dockerHubLoginResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(&loginResp); err != nil {
+		return "", err
+	}
+	if loginResp.Token == "" {
+		return "", fmt.Errorf("Docker Hub returned no token")
+	}
+
+	return loginResp.Token, nil
+}
+
+func (a *dockerHubAdapter) getPages(req AdapterRequest, query string, header http.Header, handle func(result json.RawMessage) error) error {
+	return getPages(req.Client, query, header, func(body []byte) (string, error) {
+		page := dockerHubPage{}
+		if err := json.Unmarshal(body, &page); err != nil {
+			return "", err
+		}
+		for _, result := range page.Results {
+			if err := handle(result); err != nil {
+				return "", err
+			}
+		}
+		return page.Next, nil
+	})
+}
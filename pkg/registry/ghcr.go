@@ -0,0 +1,109 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// githubAPI serves the package listings of the GitHub container registry
+const githubAPI = "https://api.github.com"
+
+type githubPackage struct {
+	Name  string `json:"name"`
+	Owner struct {
+		Login string `json:"login"`
+	} `json:"owner"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type githubOrg struct {
+	Login string `json:"login"`
+}
+
+// ghcrAdapter lists the container packages of the user and of their
+// organizations through the GitHub API, which takes the personal access
+// token used for the registry. It needs the read:packages scope.
+type ghcrAdapter struct{}
+
+func (a *ghcrAdapter) Name() string {
+	return "GitHub"
+}
+
+func (a *ghcrAdapter) Detect(req AdapterRequest) bool {
+	return req.Hostname == "ghcr.io"
+}
+
+func (a *ghcrAdapter) Repositories(req AdapterRequest) ([]Repository, error) {
+
+	if req.Password == "" {
+		return nil, ErrNotSupported
+	}
+
+	header := bearerHeader(req.Password)
+	header.Set("Accept", "application/vnd.github+json")
+
+	repos, err := a.packages(req, githubAPI+"/user/packages?package_type=container&per_page=100", header)
+	if err != nil {
+		return nil, err
+	}
+
+	orgs := []githubOrg{}
+	err = getPages(req.Client, githubAPI+"/user/orgs?per_page=100", header, func(body []byte) (string, error) {
+		page := []githubOrg{}
+		if err := json.Unmarshal(body, &page); err != nil {
+			return "", err
+		}
+		orgs = append(orgs, page...)
+		return "", nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	for _, repo := range repos {
+		seen[repo.Name] = true
+	}
+
+	for _, org := range orgs {
+		orgRepos, err := a.packages(req, fmt.Sprintf("%s/orgs/%s/packages?package_type=container&per_page=100", githubAPI, org.Login), header)
+		if err != nil {
+			// organizations can restrict access to their packages
+			fmt.Fprintf(os.Stderr, "WARNING: listing the packages of %s: %v\n", org.Login, err)
+			continue
+		}
+		for _, repo := range orgRepos {
+			if !seen[repo.Name] {
+				seen[repo.Name] = true
+				repos = append(repos, repo)
+			}
+		}
+	}
+
+	return repos, nil
+}
+
+func (a *ghcrAdapter) packages(req AdapterRequest, query string, header http.Header) ([]Repository, error) {
+
+	repos := []Repository{}
+	err := getPages(req.Client, query, header, func(body []byte) (string, error) {
+		packages := []githubPackage{}
+		if err := json.Unmarshal(body, &packages); err != nil {
+			return "", err
+		}
+		for _, pkg := range packages {
+			repos = append(repos, Repository{
+				Name:      strings.ToLower(pkg.Owner.Login + "/" + pkg.Name),
+				PushedAt:  pkg.UpdatedAt,
+				PullCount: -1,
+			})
+		}
+		return "", nil
+	})
+
+	return repos, err
+}
@@ -0,0 +1,75 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// gitlabAPI serves the registry repositories of the projects on gitlab.com
+const gitlabAPI = "https://gitlab.com/api/v4"
+
+type gitlabProject struct {
+	ID int64 `json:"id"`
+}
+
+type gitlabRepository struct {
+	Path string `json:"path"`
+}
+
+// gitlabAdapter lists the registry repositories of every project the user
+// is a member of. It needs a personal access token with the read_api scope,
+// and only knows the API of registry.gitlab.com.
+type gitlabAdapter struct{}
+
+func (a *gitlabAdapter) Name() string {
+	return "GitLab"
+}
+
+func (a *gitlabAdapter) Detect(req AdapterRequest) bool {
+	return req.Hostname == "registry.gitlab.com"
+}
+
+func (a *gitlabAdapter) Repositories(req AdapterRequest) ([]Repository, error) {
+
+	if req.Password == "" {
+		return nil, ErrNotSupported
+	}
+
+	header := bearerHeader(req.Password)
+
+	projects := []gitlabProject{}
+	err := getPages(req.Client, gitlabAPI+"/projects?membership=true&simple=true&per_page=100", header, func(body []byte) (string, error) {
+		page := []gitlabProject{}
+		if err := json.Unmarshal(body, &page); err != nil {
+			return "", err
+		}
+		projects = append(projects, page...)
+		return "", nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	repos := []Repository{}
+	for _, project := range projects {
+		err := getPages(req.Client, fmt.Sprintf("%s/projects/%d/registry/repositories?per_page=100", gitlabAPI, project.ID), header, func(body []byte) (string, error) {
+			page := []gitlabRepository{}
+			if err := json.Unmarshal(body, &page); err != nil {
+				return "", err
+			}
+			for _, repo := range page {
+				repos = append(repos, Repository{Name: repo.Path, PullCount: -1})
+			}
+			return "", nil
+		})
+		if IsForbidden(err) || IsNotFound(err) {
+			// the container registry of the project is disabled
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return repos, nil
+}
@@ -0,0 +1,77 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type harborSystemInfo struct {
+	AuthMode string `json:"auth_mode"`
+}
+
+type harborRepository struct {
+	Name       string    `json:"name"`
+	PullCount  int64     `json:"pull_count"`
+	UpdateTime time.Time `json:"update_time"`
+}
+
+// harborAdapter lists the repositories of every project the user can see.
+// Harbor runs on any host, so it is detected by its system info endpoint,
+// and only when probing registries is allowed.
+type harborAdapter struct {
+	mu sync.Mutex
+	// detected remembers the probe of each host, as every client asks again
+	detected map[string]bool
+}
+
+func (a *harborAdapter) Name() string {
+	return "Harbor"
+}
+
+func (a *harborAdapter) Detect(req AdapterRequest) bool {
+
+	if !req.Probe {
+		return false
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if detected, ok := a.detected[req.Hostname]; ok {
+		return detected
+	}
+	if a.detected == nil {
+		a.detected = map[string]bool{}
+	}
+
+	info := harborSystemInfo{}
+	err := getJSON(req.Client, fmt.Sprintf("https://%s/api/v2.0/systeminfo", req.Hostname), nil, &info)
+	a.detected[req.Hostname] = err == nil && info.AuthMode != ""
+
+	return a.detected[req.Hostname]
+}
+
+func (a *harborAdapter) Repositories(req AdapterRequest) ([]Repository, error) {
+
+	header := basicHeader(req.Username, req.Password)
+
+	repos := []Repository{}
+	err := getPages(req.Client, fmt.Sprintf("https://%s/api/v2.0/repositories?page_size=100", req.Hostname), header, func(body []byte) (string, error) {
+		page := []harborRepository{}
+		if err := json.Unmarshal(body, &page); err != nil {
+			return "", err
+		}
+		for _, repo := range page {
+			repos = append(repos, Repository{
+				Name:      repo.Name,
+				PushedAt:  repo.UpdateTime,
+				PullCount: repo.PullCount,
+			})
+		}
+		return "", nil
+	})
+
+	return repos, err
+}
@@ -0,0 +1,226 @@
+package registry
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/docker/distribution/manifest/manifestlist"
+	"github.com/docker/distribution/manifest/schema1"
+	"github.com/docker/distribution/manifest/schema2"
+	digest "github.com/opencontainers/go-digest"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// manifestMediaTypes lists every manifest format bupkis understands, most preferred first
+var manifestMediaTypes = []string{
+	v1.MediaTypeImageIndex,
+	manifestlist.MediaTypeManifestList,
+	v1.MediaTypeImageManifest,
+	schema2.MediaTypeManifest,
+	schema1.MediaTypeSignedManifest,
+	schema1.MediaTypeManifest,
+}
+
+// Manifest represents the raw bytes of a manifest as stored in the registry
+type Manifest struct {
+	MediaType string
+	Digest    digest.Digest
+	Content   []byte
+}
+
+// IsIndex reports whether the manifest references other manifests rather than layers
+func (m Manifest) IsIndex() bool {
+	return m.MediaType == v1.MediaTypeImageIndex || m.MediaType == manifestlist.MediaTypeManifestList
+}
+
+// IsSchema1 reports whether the manifest uses the deprecated docker schema1 format
+func (m Manifest) IsSchema1() bool {
+	return m.MediaType == schema1.MediaTypeSignedManifest || m.MediaType == schema1.MediaTypeManifest
+}
+
+// References returns the descriptors the manifest points at: the child
+// manifests of an index, or the config and layers of an image.
+func (m Manifest) References() ([]v1.Descriptor, error) {
+	if m.IsSchema1() {
+		return nil, fmt.Errorf("schema1 manifest %s is not supported", m.Digest)
+	}
+
+	if m.IsIndex() {
+		index := v1.Index{}
+		if err := json.Unmarshal(m.Content, &index); err != nil {
+			return nil, err
+		}
+
+		return index.Manifests, nil
+	}
+
+	// docker schema2 shares its layout with the OCI image manifest
+	image := v1.Manifest{}
+	if err := json.Unmarshal(m.Content, &image); err != nil {
+		return nil, err
+	}
+
+	return append([]v1.Descriptor{image.Config}, image.Layers...), nil
+}
+
+// Descriptor describes the manifest itself, as it would be referenced from an index
+func (m Manifest) Descriptor() v1.Descriptor {
+	return v1.Descriptor{
+		MediaType: m.MediaType,
+		Digest:    m.Digest,
+		Size:      int64(len(m.Content)),
+	}
+}
+
+// GetManifest fetches a manifest by tag or digest without re-encoding it, so
+// the returned content hashes to the same digest the registry serves.
+func (rc *registryClient) GetManifest(hostname string, repo string, reference string) (Manifest, error) {
+
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("https://%s/v2/%s/manifests/%s", hostname, repo, reference), nil)
+	if err != nil {
+		return Manifest{}, err
+	}
+	req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+
+	resp, err := rc.do(hostname, req)
+	if err != nil {
+		return Manifest{}, err
+	}
+	defer resp.Body.Close()
+
+	content, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == "" || mediaType == "application/json" || mediaType == "text/plain" {
+		mediaType = detectManifestMediaType(content)
+	}
+
+	dgst := digest.FromBytes(content)
+	if header := resp.Header.Get("Docker-Content-Digest"); header != "" {
+		headerDigest, err := digest.Parse(header)
+		if err != nil {
+			return Manifest{}, fmt.Errorf("invalid manifest digest %q: %v", header, err)
+		}
+		// schema1 digests are computed over the unsigned payload, so only
+		// the newer formats can be checked against the raw bytes.
+		if headerDigest != dgst && mediaType != schema1.MediaTypeSignedManifest {
+			return Manifest{}, fmt.Errorf("manifest digest mismatch: registry reported %s, content is %s", headerDigest, dgst)
+		}
+		dgst = headerDigest
+	}
+
+	// a manifest asked for by digest is only trusted when it is that manifest
+	if requested, err := digest.Parse(reference); err == nil && dgst != requested {
+		return Manifest{}, fmt.Errorf("manifest %s: registry returned %s", requested, dgst)
+	}
+
+	return Manifest{
+		MediaType: mediaType,
+		Digest:    dgst,
+		Content:   content,
+	}, nil
+}
+
+// PutManifest uploads the manifest content unchanged under the given reference
+// and returns the digest reported by the registry.
+func (rc *registryClient) PutManifest(hostname string, repo string, reference string, manifest Manifest) (digest.Digest, error) {
+
+	req, err := http.NewRequest(http.MethodPut, fmt.Sprintf("https://%s/v2/%s/manifests/%s", hostname, repo, reference), bytes.NewReader(manifest.Content))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", manifest.MediaType)
+
+	resp, err := rc.do(hostname, req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if header := resp.Header.Get("Docker-Content-Digest"); header != "" {
+		return digest.Parse(header)
+	}
+
+	return digest.FromBytes(manifest.Content), nil
+}
+
+// GetManifestDigest resolves a reference to the digest of its manifest,
+// without downloading the manifest when the registry reports the digest.
+func (rc *registryClient) GetManifestDigest(hostname string, repo string, reference string) (digest.Digest, error) {
+
+	req, err := http.NewRequest(http.MethodHead, fmt.Sprintf("https://%s/v2/%s/manifests/%s", hostname, repo, reference), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+
+	resp, err := rc.do(hostname, req)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+
+	if header := resp.Header.Get("Docker-Content-Digest"); header != "" {
+		return digest.Parse(header)
+	}
+
+	manifest, err := rc.GetManifest(hostname, repo, reference)
+	if err != nil {
+		return "", err
+	}
+
+	return manifest.Digest, nil
+}
+
+// DeleteManifest removes a manifest from the repository, along with every
+// tag pointing at it. The distribution API only deletes manifests by digest.
+func (rc *registryClient) DeleteManifest(hostname string, repo string, dgst digest.Digest) error {
+
+	req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("https://%s/v2/%s/manifests/%s", hostname, repo, dgst), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := rc.do(hostname, req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+// detectManifestMediaType guesses the media type of a manifest served without a usable Content-Type
+func detectManifestMediaType(content []byte) string {
+	probe := struct {
+		SchemaVersion int               `json:"schemaVersion"`
+		MediaType     string            `json:"mediaType"`
+		Manifests     []json.RawMessage `json:"manifests"`
+		Signatures    []json.RawMessage `json:"signatures"`
+	}{}
+
+	if err := json.Unmarshal(content, &probe); err != nil {
+		return ""
+	}
+
+	switch {
+	case probe.MediaType != "":
+		return probe.MediaType
+	case probe.SchemaVersion == 1 && len(probe.Signatures) != 0:
+		return schema1.MediaTypeSignedManifest
+	case probe.SchemaVersion == 1:
+		return schema1.MediaTypeManifest
+	case probe.Manifests != nil:
+		return v1.MediaTypeImageIndex
+	default:
+		return v1.MediaTypeImageManifest
+	}
+}
@@ -0,0 +1,139 @@
+package registry
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/docker/distribution/manifest/manifestlist"
+	"github.com/docker/distribution/manifest/schema1"
+	digest "github.com/opencontainers/go-digest"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// testManifest is formatted unlike a JSON encoder would, so that re-encoding it changes the digest
+var testManifest = []byte(`{
+   "schemaVersion": 2,
+   "mediaType": "application/vnd.oci.image.manifest.v1+json",
+   "config": {"mediaType": "application/vnd.oci.image.config.v1+json", "digest": "sha256:44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a", "size": 2},
+   "layers": []
+}`)
+
+func TestGetManifest(t *testing.T) {
+
+	rc := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", v1.MediaTypeImageManifest)
+		w.Header().Set("Docker-Content-Digest", digest.FromBytes(testManifest).String())
+		w.Write(testManifest)
+	}))
+
+	manifest, err := rc.GetManifest(testHostname, "app", "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(manifest.Content, testManifest) {
+		t.Errorf("GetManifest content = %s, want the bytes served", manifest.Content)
+	}
+	if manifest.Digest != digest.FromBytes(testManifest) || manifest.MediaType != v1.MediaTypeImageManifest {
+		t.Errorf("GetManifest = %s %s, want %s %s", manifest.MediaType, manifest.Digest, v1.MediaTypeImageManifest, digest.FromBytes(testManifest))
+	}
+}
+
+func TestGetManifestDigestMismatch(t *testing.T) {
+
+	rc := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", v1.MediaTypeImageManifest)
+		w.Header().Set("Docker-Content-Digest", digest.FromString("other").String())
+		w.Write(testManifest)
+	}))
+
+	if _, err := rc.GetManifest(testHostname, "app", "v1"); err == nil {
+		t.Error("GetManifest accepted content that does not match Docker-Content-Digest")
+	}
+}
+
+func TestPutManifest(t *testing.T) {
+
+	var put []byte
+	var contentType string
+	rc := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || r.URL.Path != "/v2/app/manifests/stable" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+		put, _ = ioutil.ReadAll(r.Body)
+		contentType = r.Header.Get("Content-Type")
+		w.Header().Set("Docker-Content-Digest", digest.FromBytes(put).String())
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	manifest := Manifest{MediaType: v1.MediaTypeImageManifest, Digest: digest.FromBytes(testManifest), Content: testManifest}
+	dgst, err := rc.PutManifest(testHostname, "app", "stable", manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(put, testManifest) || contentType != v1.MediaTypeImageManifest {
+		t.Errorf("PutManifest sent %s as %s, want the manifest unchanged", put, contentType)
+	}
+	if dgst != manifest.Digest {
+		t.Errorf("PutManifest digest = %s, want %s", dgst, manifest.Digest)
+	}
+}
+
+func TestDetectManifestMediaType(t *testing.T) {
+
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{`{"schemaVersion": 2, "mediaType": "` + manifestlist.MediaTypeManifestList + `"}`, manifestlist.MediaTypeManifestList},
+		{`{"schemaVersion": 2, "manifests": []}`, v1.MediaTypeImageIndex},
+		{`{"schemaVersion": 2, "config": {}, "layers": []}`, v1.MediaTypeImageManifest},
+		{`{"schemaVersion": 1, "signatures": [{}]}`, schema1.MediaTypeSignedManifest},
+		{`{"schemaVersion": 1}`, schema1.MediaTypeManifest},
+		{`not json`, ""},
+	}
+
+	for _, test := range tests {
+		if got := detectManifestMediaType([]byte(test.content)); got != test.want {
+			t.Errorf("detectManifestMediaType(%s) = %q, want %q", test.content, got, test.want)
+		}
+	}
+}
+
+func TestGetManifestByDigest(t *testing.T) {
+
+	other := []byte(`{"schemaVersion": 2, "mediaType": "application/vnd.oci.image.manifest.v1+json", "layers": []}`)
+
+	tests := []struct {
+		name   string
+		header bool
+		served []byte
+		ok     bool
+	}{
+		{"requested manifest", true, testManifest, true},
+		{"requested manifest without digest header", false, testManifest, true},
+		{"other manifest with its digest header", true, other, false},
+		{"other manifest without digest header", false, other, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rc := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", v1.MediaTypeImageManifest)
+				if test.header {
+					w.Header().Set("Docker-Content-Digest", digest.FromBytes(test.served).String())
+				}
+				w.Write(test.served)
+			}))
+
+			_, err := rc.GetManifest(testHostname, "app", digest.FromBytes(testManifest).String())
+			if test.ok && err != nil {
+				t.Errorf("GetManifest: %v", err)
+			}
+			if !test.ok && err == nil {
+				t.Error("GetManifest accepted a manifest other than the digest asked for")
+			}
+		})
+	}
+}
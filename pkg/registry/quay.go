@@ -0,0 +1,92 @@
+package registry
+
+import (
+	"encoding/json"
+	"net/url"
+	"time"
+)
+
+const (
+	// quayAPI serves the repository listings of quay.io
+	quayAPI = "https://quay.io/api/v1"
+	// quayOAuthUsername is the username that logs in to quay.io with an OAuth token
+	quayOAuthUsername = "$oauthtoken"
+)
+
+type quayUser struct {
+	Username      string `json:"username"`
+	Organizations []struct {
+		Name string `json:"name"`
+	} `json:"organizations"`
+}
+
+type quayRepositoriesResponse struct {
+	Repositories []struct {
+		Namespace    string `json:"namespace"`
+		Name         string `json:"name"`
+		LastModified *int64 `json:"last_modified"`
+	} `json:"repositories"`
+	NextPage string `json:"next_page"`
+}
+
+// quayAdapter lists the repositories of the user and of their organizations.
+// The API only takes OAuth tokens, so robot accounts use the catalog.
+type quayAdapter struct{}
+
+func (a *quayAdapter) Name() string {
+	return "Quay"
+}
+
+func (a *quayAdapter) Detect(req AdapterRequest) bool {
+	return req.Hostname == "quay.io"
+}
+
+func (a *quayAdapter) Repositories(req AdapterRequest) ([]Repository, error) {
+
+	if req.Password == "" || (req.Username != "" && req.Username != quayOAuthUsername) {
+		return nil, ErrNotSupported
+	}
+
+	header := bearerHeader(req.Password)
+
+	user := quayUser{}
+	if err := getJSON(req.Client, quayAPI+"/user/", header, &user); err != nil {
+		return nil, err
+	}
+
+	namespaces := []string{user.Username}
+	for _, org := range user.Organizations {
+		namespaces = append(namespaces, org.Name)
+	}
+
+	repos := []Repository{}
+	for _, namespace := range namespaces {
+		query := url.Values{}
+		query.Set("namespace", namespace)
+		query.Set("last_modified", "true")
+		base := quayAPI + "/repository?" + query.Encode()
+
+		err := getPages(req.Client, base, header, func(body []byte) (string, error) {
+			page := quayRepositoriesResponse{}
+			if err := json.Unmarshal(body, &page); err != nil {
+				return "", err
+			}
+			for _, repo := range page.Repositories {
+				r := Repository{Name: repo.Namespace + "/" + repo.Name, PullCount: -1}
+				if repo.LastModified != nil {
+					r.PushedAt = time.Unix(*repo.LastModified, 0).UTC()
+				}
+				repos = append(repos, r)
+			}
+			if page.NextPage == "" {
+				return "", nil
+			}
+			return base + "&next_page=" + url.QueryEscape(page.NextPage), nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return repos, nil
+}
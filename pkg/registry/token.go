@@ -0,0 +1,274 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/distribution/registry/client/auth/challenge"
+)
+
+// defaultTokenExpiry is how long a token without expires_in is valid, as set by the token spec
+const defaultTokenExpiry = 60 * time.Second
+
+// repositoryPathRegexp extracts the repository from the path of a registry API request
+var repositoryPathRegexp = regexp.MustCompile(`^/v2/(.+)/(manifests|blobs|tags)/`)
+
+// TokenTransport authenticates requests to a registry. It sends basic auth
+// until the registry answers with a Bearer challenge, and then exchanges the
+// credential for an access token at the token realm: an identity token, which
+// has no username, through the OAuth2 refresh_token grant, and a username and
+// password through basic auth on the realm. An Azure Active Directory access
+// token given for an Azure Container Registry is first exchanged for an ACR
+// refresh token.
+//
+// Tokens are cached per repository, so blob uploads, whose bodies cannot be
+// sent twice, use the token of the requests that opened them.
+type TokenTransport struct {
+	Transport http.RoundTripper
+	URL       string
+	Username  string
+	Password  string
+
+	mu        sync.Mutex
+	challenge *challenge.Challenge
+	tokens    map[string]accessToken
+	// refreshToken is the ACR refresh token an AAD access token was exchanged for
+	refreshToken string
+}
+
+type accessToken struct {
+	token   string
+	expires time.Time
+}
+
+type tokenResponse struct {
+	Token       string `json:"token"`
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+func (t *TokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	if req.URL.Host != t.URL {
+		return t.Transport.RoundTrip(req)
+	}
+
+	key := tokenKey(req.URL.Path)
+	authReq := req.Clone(req.Context())
+
+	// tokenErr is kept to tell why the registry answers 401
+	var tokenErr error
+	if token, ok := t.cachedToken(key); ok {
+		authReq.Header.Set("Authorization", "Bearer "+token)
+	} else if bearer := t.bearerChallenge(); bearer != nil && !isReplayable(req) {
+		// the body cannot be sent again after a challenge, so get the token first
+		token, err := t.fetchToken(*bearer, defaultScope(key))
+		if err != nil {
+			tokenErr = fmt.Errorf("getting a token from %s: %v", bearer.Parameters["realm"], err)
+		} else {
+			t.storeToken(key, token)
+			authReq.Header.Set("Authorization", "Bearer "+token.token)
+		}
+	} else if t.Username != "" || t.Password != "" {
+		authReq.SetBasicAuth(t.Username, t.Password)
+	}
+
+	resp, err := t.Transport.RoundTrip(authReq)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	bearer := responseBearerChallenge(resp)
+	if bearer == nil || !isReplayable(req) {
+		return unauthorized(resp, tokenErr)
+	}
+
+	t.mu.Lock()
+	t.challenge = bearer
+	t.mu.Unlock()
+
+	token, err := t.fetchToken(*bearer, bearer.Parameters["scope"])
+	if err != nil {
+		return unauthorized(resp, fmt.Errorf("getting a token from %s: %v", bearer.Parameters["realm"], err))
+	}
+	t.storeToken(key, token)
+
+	retryReq := req.Clone(req.Context())
+	if req.GetBody != nil {
+		retryReq.Body, err = req.GetBody()
+		if err != nil {
+			return unauthorized(resp, fmt.Errorf("sending the request again with a token: %v", err))
+		}
+	}
+	retryReq.Header.Set("Authorization", "Bearer "+token.token)
+
+	resp.Body.Close()
+	return t.Transport.RoundTrip(retryReq)
+}
+
+// unauthorized returns a 401 response as it is, or as an HTTPStatusError
+// telling why no token could be sent.
+func unauthorized(resp *http.Response, cause error) (*http.Response, error) {
+
+	if cause == nil {
+		return resp, nil
+	}
+
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("http: failed to read response body (status=%v, err=%q)", resp.StatusCode, err)
+	}
+
+	return nil, &HTTPStatusError{Response: resp, Body: body, Cause: cause}
+}
+
+// fetchToken exchanges the credential for an access token at the realm of the challenge
+func (t *TokenTransport) fetchToken(bearer challenge.Challenge, scope string) (accessToken, error) {
+
+	realm, err := url.Parse(bearer.Parameters["realm"])
+	if err != nil || realm.Scheme == "" {
+		return accessToken{}, fmt.Errorf("invalid token realm %q", bearer.Parameters["realm"])
+	}
+
+	username, password := t.Username, t.Password
+	if IsACR(t.URL) && isAADToken(password) {
+		refreshToken, err := t.acrRefreshToken(realm, bearer.Parameters["service"])
+		if err != nil {
+			return accessToken{}, err
+		}
+		username, password = "", refreshToken
+	}
+
+	var req *http.Request
+	if username == "" && password != "" {
+		form := url.Values{}
+		form.Set("grant_type", "refresh_token")
+		form.Set("refresh_token", password)
+		form.Set("service", bearer.Parameters["service"])
+		form.Set("client_id", "bupkis")
+		if scope != "" {
+			form.Set("scope", scope)
+		}
+
+		req, err = http.NewRequest(http.MethodPost, realm.String(), strings.NewReader(form.Encode()))
+		if err != nil {
+			return accessToken{}, err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	} else {
+		query := realm.Query()
+		query.Set("service", bearer.Parameters["service"])
+		for _, s := range strings.Fields(scope) {
+			query.Add("scope", s)
+		}
+		realm.RawQuery = query.Encode()
+
+		req, err = http.NewRequest(http.MethodGet, realm.String(), nil)
+		if err != nil {
+			return accessToken{}, err
+		}
+		if username != "" {
+			req.SetBasicAuth(username, password)
+		}
+	}
+
+	resp, err := (&http.Client{Transport: t.Transport}).Do(req)
+	if err != nil {
+		return accessToken{}, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return accessToken{}, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return accessToken{}, &HTTPStatusError{Response: resp, Body: body}
+	}
+
+	tokenResp := tokenResponse{}
+	if err := json.Unmarshal(body, &tokenResp); err != nil {
+		return accessToken{}, err
+	}
+
+	token := accessToken{token: tokenResp.AccessToken, expires: time.Now().Add(defaultTokenExpiry)}
+	if token.token == "" {
+		token.token = tokenResp.Token
+	}
+	if token.token == "" {
+		return accessToken{}, fmt.Errorf("token realm %s returned no token", realm.Host)
+	}
+	if tokenResp.ExpiresIn > 0 {
+		token.expires = time.Now().Add(time.Duration(tokenResp.ExpiresIn) * time.Second)
+	}
+
+	return token, nil
+}
+
+func (t *TokenTransport) cachedToken(key string) (string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	token, ok := t.tokens[key]
+	// leave some time for the request to reach the registry
+	if !ok || time.Now().Add(10*time.Second).After(token.expires) {
+		return "", false
+	}
+
+	return token.token, true
+}
+
+func (t *TokenTransport) storeToken(key string, token accessToken) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.tokens == nil {
+		t.tokens = map[string]accessToken{}
+	}
+	t.tokens[key] = token
+}
+
+func (t *TokenTransport) bearerChallenge() *challenge.Challenge {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.challenge
+}
+
+func responseBearerChallenge(resp *http.Response) *challenge.Challenge {
+	for _, c := range challenge.ResponseChallenges(resp) {
+		if strings.EqualFold(c.Scheme, "bearer") {
+			return &c
+		}
+	}
+	return nil
+}
+
+// tokenKey is the repository a request is for, or the endpoint for requests
+// outside of a repository such as the catalog
+func tokenKey(path string) string {
+	if match := repositoryPathRegexp.FindStringSubmatch(path); match != nil {
+		return match[1]
+	}
+	return path
+}
+
+func defaultScope(key string) string {
+	if strings.HasPrefix(key, "/") {
+		return ""
+	}
+	return fmt.Sprintf("repository:%s:pull,push", key)
+}
+
+// isReplayable reports whether the body of the request can be sent again
+func isReplayable(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
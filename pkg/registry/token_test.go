@@ -0,0 +1,164 @@
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// newTokenTestServer serves a registry that only accepts the bearer token
+// "token-<repository>", handed out by its token realm for basic auth as
+// user:pass or for the refresh token "identity".
+func newTokenTestServer(t *testing.T, tokenRequests *int) http.RoundTripper {
+
+	return newTestTransport(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		if r.URL.Path == "/token" {
+			*tokenRequests++
+
+			username, password, basic := r.BasicAuth()
+			if r.Method == http.MethodPost {
+				r.ParseForm()
+				basic = r.PostForm.Get("grant_type") == "refresh_token" && r.PostForm.Get("refresh_token") == "identity"
+				username, password = "user", "pass"
+			}
+			if !basic || username != "user" || password != "pass" {
+				http.Error(w, "denied", http.StatusUnauthorized)
+				return
+			}
+
+			scope := r.URL.Query().Get("scope")
+			if scope == "" {
+				scope = r.PostForm.Get("scope")
+			}
+			repository := strings.Split(scope, ":")[1]
+			writeJSON(w, map[string]interface{}{"token": "token-" + repository, "expires_in": 300})
+			return
+		}
+
+		repository := tokenKey(r.URL.Path)
+		if r.Header.Get("Authorization") != "Bearer token-"+repository {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="https://%s/token",service="%s",scope="repository:%s:pull"`, testHostname, testHostname, repository))
+			http.Error(w, `{"errors":[{"code":"UNAUTHORIZED"}]}`, http.StatusUnauthorized)
+			return
+		}
+
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Write(body)
+	}))
+}
+
+func tokenTestRequest(t *testing.T, client *http.Client, method string, repository string, body io.Reader) (string, error) {
+
+	req, err := http.NewRequest(method, fmt.Sprintf("https://%s/v2/%s/blobs/uploads/", testHostname, repository), body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	content, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("status %d: %s", resp.StatusCode, content)
+	}
+	return string(content), nil
+}
+
+func TestTokenTransport(t *testing.T) {
+
+	for _, credential := range []struct {
+		name     string
+		username string
+		password string
+	}{
+		{"basic auth", "user", "pass"},
+		{"identity token", "", "identity"},
+	} {
+		tokenRequests := 0
+		client := &http.Client{Transport: &TokenTransport{
+			Transport: newTokenTestServer(t, &tokenRequests),
+			URL:       testHostname,
+			Username:  credential.username,
+			Password:  credential.password,
+		}}
+
+		// the body is sent again after the challenge
+		for i := 0; i < 2; i++ {
+			got, err := tokenTestRequest(t, client, http.MethodPost, "team/app", strings.NewReader("content"))
+			if err != nil || got != "content" {
+				t.Errorf("%s: request %d = %q, %v, want the content echoed", credential.name, i, got, err)
+			}
+		}
+		if tokenRequests != 1 {
+			t.Errorf("%s: %d token requests for one repository, want the token cached", credential.name, tokenRequests)
+		}
+
+		// a body that cannot be sent twice gets a token before it is sent
+		got, err := tokenTestRequest(t, client, http.MethodPost, "team/web", ioutil.NopCloser(strings.NewReader("upload")))
+		if err != nil || got != "upload" {
+			t.Errorf("%s: upload = %q, %v, want the content echoed", credential.name, got, err)
+		}
+		if tokenRequests != 2 {
+			t.Errorf("%s: %d token requests for two repositories, want 2", credential.name, tokenRequests)
+		}
+	}
+}
+
+func TestTokenTransportDenied(t *testing.T) {
+
+	tokenRequests := 0
+	client := &http.Client{Transport: &ErrorTransport{Transport: &TokenTransport{
+		Transport: newTokenTestServer(t, &tokenRequests),
+		URL:       testHostname,
+		Username:  "user",
+		Password:  "wrong",
+	}}}
+
+	_, err := tokenTestRequest(t, client, http.MethodGet, "team/app", nil)
+	statusErr := &HTTPStatusError{}
+	if !errors.As(err, &statusErr) || statusErr.Response.StatusCode != http.StatusUnauthorized || statusErr.Cause == nil {
+		t.Fatalf("request with a wrong password failed with %v, want a 401 telling why no token was sent", err)
+	}
+	if !strings.Contains(err.Error(), "/token") {
+		t.Errorf("error %q does not name the token realm", err)
+	}
+}
+
+func TestTokenTransportGetBodyError(t *testing.T) {
+
+	tokenRequests := 0
+	client := &http.Client{Transport: &ErrorTransport{Transport: &TokenTransport{
+		Transport: newTokenTestServer(t, &tokenRequests),
+		URL:       testHostname,
+		Username:  "user",
+		Password:  "pass",
+	}}}
+
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("https://%s/v2/team/app/blobs/uploads/", testHostname), strings.NewReader("content"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.GetBody = func() (io.ReadCloser, error) {
+		return nil, fmt.Errorf("content is gone")
+	}
+
+	resp, err := client.Do(req)
+	if err == nil {
+		resp.Body.Close()
+		t.Fatalf("request whose body cannot be sent again returned %s, want an error", resp.Status)
+	}
+	if !strings.Contains(err.Error(), "content is gone") {
+		t.Errorf("error %q does not tell the body could not be sent again", err)
+	}
+}
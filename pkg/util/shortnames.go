@@ -0,0 +1,133 @@
+package util
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+	"github.com/zawachte-msft/bupkis/pkg/registry"
+)
+
+// registriesConf holds the parts of the containers registries.conf that
+// decide where short image names such as "fedora" or "team/api" are pulled from
+type registriesConf struct {
+	UnqualifiedSearchRegistries []string          `toml:"unqualified-search-registries"`
+	Aliases                     map[string]string `toml:"aliases"`
+}
+
+var (
+	loadRegistriesConfOnce sync.Once
+	loadedRegistriesConf   registriesConf
+)
+
+// registriesConfPaths returns the registries.conf files in the order podman
+// reads them: CONTAINERS_REGISTRIES_CONF alone, or the system file followed
+// by the user file, each followed by the drop-ins of its .d directory.
+func registriesConfPaths() []string {
+
+	if path := os.Getenv("CONTAINERS_REGISTRIES_CONF"); path != "" {
+		return []string{path}
+	}
+
+	files := []string{"/etc/containers/registries.conf"}
+	if home, err := os.UserHomeDir(); err == nil {
+		files = append(files, filepath.Join(home, ".config", "containers", "registries.conf"))
+	}
+
+	paths := []string{}
+	for _, file := range files {
+		paths = append(paths, file)
+
+		dropIns, _ := filepath.Glob(file + ".d/*.conf")
+		sort.Strings(dropIns)
+		paths = append(paths, dropIns...)
+	}
+
+	return paths
+}
+
+// getRegistriesConf merges the registries.conf files. Later files replace the
+// search registries and add to or override the aliases of earlier ones.
+func getRegistriesConf() registriesConf {
+
+	loadRegistriesConfOnce.Do(func() {
+		loadedRegistriesConf.Aliases = map[string]string{}
+
+		for _, path := range registriesConfPaths() {
+			conf := registriesConf{}
+			if _, err := toml.DecodeFile(path, &conf); err != nil {
+				if !os.IsNotExist(err) {
+					fmt.Fprintf(os.Stderr, "WARNING: reading %s: %v\n", path, err)
+				}
+				continue
+			}
+
+			if conf.UnqualifiedSearchRegistries != nil {
+				loadedRegistriesConf.UnqualifiedSearchRegistries = conf.UnqualifiedSearchRegistries
+			}
+			for name, alias := range conf.Aliases {
+				loadedRegistriesConf.Aliases[name] = alias
+			}
+		}
+	})
+
+	return loadedRegistriesConf
+}
+
+// isQualified reports whether the first path segment of the image name is a
+// registry, as opposed to the namespace of a short name. Without a path, a
+// colon starts a tag as in fedora:39, unless the host is clearly one.
+func isQualified(imageName string) bool {
+	segments := strings.Split(stripDigest(imageName), "/")
+	if len(segments) == 1 {
+		host := strings.Split(segments[0], ":")[0]
+		return strings.Contains(host, ".") || host == "localhost"
+	}
+	return strings.ContainsAny(segments[0], ".:") || segments[0] == "localhost"
+}
+
+// ParseImageReference parses an image given on the command line, resolving
+// short names the way podman does. Only commands that take an image, and so
+// always a repository, use it: hostnames and registry/repository targets go
+// through ParseImageName, as a bare registry would look like a short name.
+func ParseImageReference(imageName string) registry.ImageData {
+	return ParseImageName(resolveShortName(imageName))
+}
+
+// resolveShortName qualifies a short image name through the aliases of
+// registries.conf, or else with the first unqualified search registry. Names
+// are left as they are when neither is configured.
+func resolveShortName(imageName string) string {
+
+	if imageName == "" || isQualified(imageName) {
+		return imageName
+	}
+
+	conf := getRegistriesConf()
+
+	name := stripDigest(imageName)
+	suffix := imageName[len(name):]
+	if index := strings.LastIndex(name, ":"); index != -1 && !strings.Contains(name[index:], "/") {
+		suffix = name[index:] + suffix
+		name = name[:index]
+	}
+
+	if alias, ok := conf.Aliases[name]; ok {
+		return alias + suffix
+	}
+
+	if len(conf.UnqualifiedSearchRegistries) == 0 {
+		return imageName
+	}
+
+	registry := conf.UnqualifiedSearchRegistries[0]
+	if registry == "docker.io" && !strings.Contains(name, "/") {
+		name = "library/" + name
+	}
+
+	return registry + "/" + name + suffix
+}
@@ -0,0 +1,106 @@
+package util
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// useRegistriesConf points the short name resolution at a registries.conf with content
+func useRegistriesConf(t *testing.T, content string) {
+
+	path := filepath.Join(t.TempDir(), "registries.conf")
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONTAINERS_REGISTRIES_CONF", path)
+
+	resetRegistriesConf(t)
+}
+
+// resetRegistriesConf makes the next lookup read registries.conf again
+func resetRegistriesConf(t *testing.T) {
+	reset := func() {
+		loadRegistriesConfOnce = sync.Once{}
+		loadedRegistriesConf = registriesConf{}
+	}
+	reset()
+	t.Cleanup(reset)
+}
+
+func TestResolveShortName(t *testing.T) {
+
+	useRegistriesConf(t, `
+unqualified-search-registries = ["docker.io", "quay.io"]
+
+[aliases]
+"fedora" = "registry.fedoraproject.org/fedora"
+"team/api" = "registry.example.com/team/api"
+`)
+
+	tests := map[string]string{
+		"fedora":                           "registry.fedoraproject.org/fedora",
+		"fedora:39":                        "registry.fedoraproject.org/fedora:39",
+		"team/api@sha256:abc":              "registry.example.com/team/api@sha256:abc",
+		"alpine":                           "docker.io/library/alpine",
+		"alpine:3.18":                      "docker.io/library/alpine:3.18",
+		"bitnami/nginx":                    "docker.io/bitnami/nginx",
+		"registry.example.com/app:1":       "registry.example.com/app:1",
+		"localhost:5000/app":               "localhost:5000/app",
+		"localhost/app":                    "localhost/app",
+		"registry.example.com:5000/team/a": "registry.example.com:5000/team/a",
+		"":                                 "",
+	}
+
+	for name, want := range tests {
+		if got := resolveShortName(name); got != want {
+			t.Errorf("resolveShortName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestResolveShortNameUnconfigured(t *testing.T) {
+
+	useRegistriesConf(t, "")
+
+	if got := resolveShortName("alpine:3.18"); got != "alpine:3.18" {
+		t.Errorf("resolveShortName(alpine:3.18) = %q without search registries, want it unchanged", got)
+	}
+}
+
+func TestRegistriesConfDropIns(t *testing.T) {
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("CONTAINERS_REGISTRIES_CONF", "")
+	resetRegistriesConf(t)
+
+	confDir := filepath.Join(home, ".config", "containers")
+	files := map[string]string{
+		"registries.conf":             "unqualified-search-registries = [\"docker.io\"]\n[aliases]\n\"app\" = \"a.example.com/app\"\n\"web\" = \"a.example.com/web\"\n",
+		"registries.conf.d/10-b.conf": "unqualified-search-registries = [\"quay.io\"]\n[aliases]\n\"app\" = \"b.example.com/app\"\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(confDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// drop-ins replace the search registries and override single aliases
+	tests := map[string]string{
+		"app":    "b.example.com/app",
+		"web":    "a.example.com/web",
+		"alpine": "quay.io/alpine",
+	}
+	for name, want := range tests {
+		if got := resolveShortName(name); got != want {
+			t.Errorf("resolveShortName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/docker/go-units"
+	digest "github.com/opencontainers/go-digest"
 	"github.com/zawachte-msft/bupkis/pkg/registry"
 )
 
@@ -32,6 +33,7 @@ func ParseImageName(imageName string) registry.ImageData {
 	returnData.Hostname = GetHostnameFromImage(imageName)
 	returnData.Name = GetRepositoryFromImage(imageName)
 	returnData.Tag = GetTagFromImage(imageName)
+	returnData.Digest = digest.Digest(GetDigestFromImage(imageName))
 	return returnData
 }
 
@@ -41,19 +43,36 @@ func GetHostnameFromImage(imageName string) string {
 }
 
 func GetTagFromImage(imageName string) string {
-	if !strings.Contains(imageName, ":") {
+	delimbedImageName := strings.Split(stripDigest(imageName), "/")
+	lastSegment := delimbedImageName[len(delimbedImageName)-1]
+
+	// a port on the hostname is not a tag
+	if len(delimbedImageName) == 1 || !strings.Contains(lastSegment, ":") {
 		return ""
 	}
 
-	delimbedImageName := strings.Split(imageName, ":")
+	delimbedLastSegment := strings.Split(lastSegment, ":")
+	return delimbedLastSegment[len(delimbedLastSegment)-1]
+}
+
+func GetDigestFromImage(imageName string) string {
+	if !strings.Contains(imageName, "@") {
+		return ""
+	}
+
+	delimbedImageName := strings.Split(imageName, "@")
 	return delimbedImageName[len(delimbedImageName)-1]
 }
 
 func GetRepositoryFromImage(imageName string) string {
-	delimbedImageName := strings.Split(imageName, "/")
+	delimbedImageName := strings.Split(stripDigest(imageName), "/")
 
 	stripTag := strings.Split(delimbedImageName[len(delimbedImageName)-1], ":")
 	delimbedImageName[len(delimbedImageName)-1] = stripTag[0]
 
 	return strings.Join(delimbedImageName[1:], "/")
 }
+
+func stripDigest(imageName string) string {
+	return strings.Split(imageName, "@")[0]
+}
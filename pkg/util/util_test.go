@@ -0,0 +1,31 @@
+package util
+
+import (
+	"testing"
+)
+
+func TestParseImageName(t *testing.T) {
+
+	tests := []struct {
+		image    string
+		hostname string
+		name     string
+		tag      string
+		digest   string
+	}{
+		{"registry.example.com/team/app", "registry.example.com", "team/app", "", ""},
+		{"registry.example.com/team/app:1.2", "registry.example.com", "team/app", "1.2", ""},
+		{"registry.example.com:5000/app:1.2", "registry.example.com:5000", "app", "1.2", ""},
+		{"registry.example.com:5000", "registry.example.com:5000", "", "", ""},
+		{"registry.example.com/app@sha256:abc", "registry.example.com", "app", "", "sha256:abc"},
+		{"registry.example.com/app:1.2@sha256:abc", "registry.example.com", "app", "1.2", "sha256:abc"},
+	}
+
+	for _, test := range tests {
+		got := ParseImageName(test.image)
+		if got.Hostname != test.hostname || got.Name != test.name || got.Tag != test.tag || got.Digest.String() != test.digest {
+			t.Errorf("ParseImageName(%q) = %q %q %q %q, want %q %q %q %q", test.image,
+				got.Hostname, got.Name, got.Tag, got.Digest, test.hostname, test.name, test.tag, test.digest)
+		}
+	}
+}